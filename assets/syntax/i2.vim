@@ -9,6 +9,7 @@ syntax region	String	start=+"+ skip=+\\"+ end=+"+
 
 " Statement
 syntax keyword	Keyword	        mod import export bool any
+syntax keyword	Keyword	        induction on using cases case contradiction assume
 syntax keyword	Type	        func tmpl term
 syntax keyword	Label	        this
 syntax keyword	Exception	true false
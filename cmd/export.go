@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"git.sr.ht/~lbnz/i2/verify"
+	"github.com/spf13/cobra"
+)
+
+var (
+	smtlib bool
+	outDir string
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export --smtlib [input file]",
+	Short: "Export the proof obligations of the steps as SMT-LIB 2 scripts",
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 {
+			return fmt.Errorf("must specify input file")
+		}
+		if !smtlib {
+			return fmt.Errorf("must specify export format")
+		}
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		file, err := os.ReadFile(args[0])
+		if err != nil {
+			log.Fatalf("failed to read file: %s\n", err)
+		}
+		scripts, err := verify.Export(
+			context.Background(), string(file), verify.Options{
+				File:     args[0],
+				Importer: verify.NewImporter(searchPath()...),
+			},
+		)
+		if err != nil {
+			log.Fatalf("failed to verify: %s\n", err)
+		}
+		if err := os.MkdirAll(outDir, 0o755); err != nil {
+			log.Fatalf("failed to create directory: %s\n", err)
+		}
+		for _, s := range scripts {
+			name := filepath.Join(outDir, s.Name())
+			if err := os.WriteFile(name, []byte(s.Source), 0o644); err != nil {
+				log.Fatalf("failed to write script: %s\n", err)
+			}
+			fmt.Printf("%s: %s\n", name, s.Step)
+		}
+	},
+}
+
+func init() {
+	exportCmd.Flags().BoolVar(&smtlib, "smtlib", false,
+		"export SMT-LIB 2 scripts, one per step")
+	exportCmd.Flags().StringVarP(&outDir, "output", "o", ".",
+		"the directory to write the scripts to")
+	rootCmd.AddCommand(exportCmd)
+}
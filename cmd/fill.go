@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"git.sr.ht/~lbnz/i2/verify"
+	"github.com/spf13/cobra"
+)
+
+var (
+	depth   int
+	timeout time.Duration
+)
+
+var fillCmd = &cobra.Command{
+	Use:   "fill [input file]:[line]",
+	Short: "Search for chains of justified steps proving the failing steps on a line",
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 {
+			return fmt.Errorf("must specify input file and line")
+		}
+		_, _, err := fileLine(args[0])
+		return err
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		name, line, _ := fileLine(args[0])
+		file, err := os.ReadFile(name)
+		if err != nil {
+			log.Fatalf("failed to read file: %s\n", err)
+		}
+		fills, err := verify.Search(
+			context.Background(), string(file), verify.Options{
+				File:     name,
+				Importer: verify.NewImporter(searchPath()...),
+			}, verify.SearchOptions{
+				Line: line, Depth: depth, Timeout: timeout,
+			},
+		)
+		if err != nil {
+			log.Fatalf("failed to verify: %s\n", err)
+		}
+		if len(fills) == 0 {
+			fmt.Printf("%s:%d: no failing step\n", name, line)
+			os.Exit(1)
+		}
+		ok := true
+		for _, f := range fills {
+			if f.Diagnostic != nil {
+				fmt.Printf("%s:%s: `%s': %s\n",
+					name, f.Span, f.Step, f.Diagnostic.Message)
+				ok = false
+				continue
+			}
+			fmt.Printf("%s:%s: `%s' is proven by\n%s", name, f.Span, f.Step, f.Chain)
+		}
+		if !ok {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	fillCmd.Flags().IntVar(&depth, "depth", 3,
+		"the greatest number of steps in a chain")
+	fillCmd.Flags().DurationVar(&timeout, "timeout", 10*time.Second,
+		"the time to search for each chain")
+	rootCmd.AddCommand(fillCmd)
+}
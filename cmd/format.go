@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+
+	"git.sr.ht/~lbnz/i2/internal/diag"
+	"git.sr.ht/~lbnz/i2/verify"
+)
+
+func writeJSON(w io.Writer, r *verify.Report) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(r)
+}
+
+// The SARIF 2.1.0 log, restricted to the properties we produce.
+type (
+	sarifLog struct {
+		Version string     `json:"version"`
+		Schema  string     `json:"$schema"`
+		Runs    []sarifRun `json:"runs"`
+	}
+
+	sarifRun struct {
+		Tool    sarifTool     `json:"tool"`
+		Results []sarifResult `json:"results"`
+	}
+
+	sarifTool struct {
+		Driver sarifDriver `json:"driver"`
+	}
+
+	sarifDriver struct {
+		Name           string      `json:"name"`
+		InformationURI string      `json:"informationUri"`
+		Rules          []sarifRule `json:"rules"`
+	}
+
+	sarifRule struct {
+		ID string `json:"id"`
+	}
+
+	sarifResult struct {
+		RuleID    string          `json:"ruleId"`
+		Level     string          `json:"level"`
+		Message   sarifMessage    `json:"message"`
+		Locations []sarifLocation `json:"locations,omitempty"`
+	}
+
+	sarifMessage struct {
+		Text string `json:"text"`
+	}
+
+	sarifLocation struct {
+		PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+	}
+
+	sarifPhysicalLocation struct {
+		ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+		Region           sarifRegion           `json:"region"`
+	}
+
+	sarifArtifactLocation struct {
+		URI string `json:"uri"`
+	}
+
+	sarifRegion struct {
+		StartLine   int `json:"startLine"`
+		StartColumn int `json:"startColumn"`
+		EndLine     int `json:"endLine"`
+		EndColumn   int `json:"endColumn"`
+	}
+)
+
+// sarifLevel maps a Severity onto the levels SARIF admits.
+func sarifLevel(s diag.Severity) string {
+	switch s {
+	case diag.Error:
+		return "error"
+	case diag.Warning:
+		return "warning"
+	default:
+		return "note"
+	}
+}
+
+// writeSARIF writes the diagnostics of r as a SARIF log, with one rule for
+// each diagnostic code.
+func writeSARIF(w io.Writer, uri string, r *verify.Report) error {
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           "i2",
+			InformationURI: "https://git.sr.ht/~lbnz/i2",
+			Rules:          []sarifRule{},
+		}},
+		Results: []sarifResult{},
+	}
+	rules := map[diag.Code]bool{}
+	for _, d := range r.Diagnostics {
+		if !rules[d.Code] {
+			rules[d.Code] = true
+			run.Tool.Driver.Rules = append(run.Tool.Driver.Rules,
+				sarifRule{ID: string(d.Code)})
+		}
+		text := d.Message
+		if len(d.Notes) > 0 {
+			text += "\n" + strings.Join(d.Notes, "\n")
+		}
+		res := sarifResult{
+			RuleID:  string(d.Code),
+			Level:   sarifLevel(d.Severity),
+			Message: sarifMessage{Text: text},
+		}
+		if d.Span.IsValid() {
+			res.Locations = []sarifLocation{{sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{URI: uri},
+				Region: sarifRegion{
+					StartLine:   d.Span.Start.Line,
+					StartColumn: d.Span.Start.Column,
+					EndLine:     d.Span.End.Line,
+					EndColumn:   d.Span.End.Column,
+				},
+			}}}
+		}
+		run.Results = append(run.Results, res)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(sarifLog{
+		Version: "2.1.0",
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Runs:    []sarifRun{run},
+	})
+}
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"git.sr.ht/~lbnz/i2/verify"
+	"github.com/spf13/cobra"
+)
+
+var hintCmd = &cobra.Command{
+	Use:   "hint [input file]:[line]",
+	Short: "Suggest citations for the failing or unjustified steps on a line",
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 {
+			return fmt.Errorf("must specify input file and line")
+		}
+		_, _, err := fileLine(args[0])
+		return err
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		name, line, _ := fileLine(args[0])
+		file, err := os.ReadFile(name)
+		if err != nil {
+			log.Fatalf("failed to read file: %s\n", err)
+		}
+		hints, err := verify.Suggest(
+			context.Background(), string(file), verify.Options{
+				File:     name,
+				Importer: verify.NewImporter(searchPath()...),
+			}, line,
+		)
+		if err != nil {
+			log.Fatalf("failed to verify: %s\n", err)
+		}
+		if len(hints) == 0 {
+			fmt.Printf("%s:%d: no justification found\n", name, line)
+			os.Exit(1)
+		}
+		step := ""
+		for _, h := range hints {
+			if h.Step != step {
+				step = h.Step
+				fmt.Printf("%s:%s: `%s' is justified by\n", name, h.Span, step)
+			}
+			fmt.Printf("\t{ %s }\n", h.Citation)
+		}
+	},
+}
+
+// fileLine splits an argument of the form FILE:LINE.
+func fileLine(arg string) (string, int, error) {
+	i := strings.LastIndex(arg, ":")
+	if i < 0 {
+		return "", 0, fmt.Errorf("expected FILE:LINE, got %q", arg)
+	}
+	line, err := strconv.Atoi(arg[i+1:])
+	if err != nil || line < 1 {
+		return "", 0, fmt.Errorf("invalid line %q", arg[i+1:])
+	}
+	return arg[:i], line, nil
+}
+
+func init() {
+	rootCmd.AddCommand(hintCmd)
+}
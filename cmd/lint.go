@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"context"
+	"log"
+	"os"
+
+	"git.sr.ht/~lbnz/i2/internal/diag"
+	"git.sr.ht/~lbnz/i2/verify"
+	"github.com/spf13/cobra"
+)
+
+var lintCmd = &cobra.Command{
+	Use:   "lint [input file]",
+	Short: "Report unnecessary citations, unused proofs and axioms, and steps that may be merged",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		file, err := os.ReadFile(args[0])
+		if err != nil {
+			log.Fatalf("failed to read file: %s\n", err)
+		}
+		r, err := verify.Verify(
+			context.Background(), string(file), verify.Options{
+				File:     args[0],
+				Importer: verify.NewImporter(searchPath()...),
+				Lint:     true,
+			},
+		)
+		if err != nil {
+			log.Fatalf("failed to lint: %s\n", err)
+		}
+		diag.Fprint(os.Stdout, string(file), r.Diagnostics)
+		// suggestions alone are advisory, and do not fail
+		for _, d := range r.Diagnostics {
+			if d.Severity == verify.Error || d.Severity == verify.Warning {
+				os.Exit(1)
+			}
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(lintCmd)
+}
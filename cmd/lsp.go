@@ -0,0 +1,29 @@
+package cmd
+
+import (
+	"context"
+	"log"
+	"os"
+
+	"git.sr.ht/~lbnz/i2/internal/lsp"
+	"git.sr.ht/~lbnz/i2/verify"
+	"github.com/spf13/cobra"
+)
+
+var lspCmd = &cobra.Command{
+	Use:   "lsp",
+	Short: "Serve the Language Server Protocol over stdio",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := lsp.Serve(
+			context.Background(), os.Stdin, os.Stdout,
+			verify.NewImporter(searchPath()...),
+		); err != nil {
+			log.Fatalf("lsp: %s\n", err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(lspCmd)
+}
@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"context"
+	"log"
+	"os"
+
+	"git.sr.ht/~lbnz/i2/internal/parser"
+	"git.sr.ht/~lbnz/i2/internal/repl"
+	"github.com/spf13/cobra"
+)
+
+var replCmd = &cobra.Command{
+	Use:   "repl [input file]",
+	Short: "Explore declarations and proof steps interactively",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		r := repl.New(context.Background(), os.Stdout,
+			parser.NewFileImporter(searchPath()))
+		if len(args) == 1 {
+			file, err := os.ReadFile(args[0])
+			if err != nil {
+				log.Fatalf("failed to read file: %s\n", err)
+			}
+			r.Load(args[0], string(file))
+		}
+		if err := r.Run(os.Stdin); err != nil {
+			log.Fatalf("repl: %s\n", err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(replCmd)
+}
@@ -1,14 +1,32 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
 
-	"git.sr.ht/~lbnz/i2/internal/parser"
+	"git.sr.ht/~lbnz/i2/internal/diag"
+	"git.sr.ht/~lbnz/i2/verify"
 	"github.com/spf13/cobra"
 )
 
+var (
+	format string
+	path   []string
+)
+
+// searchPath returns the directories in which to seek imported modules: those
+// given by flags, then those in the I2PATH environment variable.
+func searchPath() []string {
+	dirs := append([]string{}, path...)
+	if env := os.Getenv("I2PATH"); env != "" {
+		dirs = append(dirs, filepath.SplitList(env)...)
+	}
+	return dirs
+}
+
 var rootCmd = &cobra.Command{
 	Use:   "i2 [input file]",
 	Short: "A verifier that human (mathematicians) can use",
@@ -16,17 +34,76 @@ var rootCmd = &cobra.Command{
 		if len(args) != 1 {
 			return fmt.Errorf("must specify input file")
 		}
-		return nil
+		switch format {
+		case "text", "json", "sarif":
+			return nil
+		default:
+			return fmt.Errorf("unknown format %q", format)
+		}
 	},
 	Run: func(cmd *cobra.Command, args []string) {
 		file, err := os.ReadFile(args[0])
 		if err != nil {
 			log.Fatalf("failed to read file: %s\n", err)
 		}
-		parser.Verify(string(file))
+		r, err := verify.Verify(
+			context.Background(), string(file), verify.Options{
+				File:     args[0],
+				Importer: verify.NewImporter(searchPath()...),
+			},
+		)
+		if err != nil {
+			log.Fatalf("failed to verify: %s\n", err)
+		}
+		switch format {
+		case "text":
+			printReport(r)
+			diag.Fprint(os.Stderr, string(file), r.Diagnostics)
+		case "json":
+			err = writeJSON(os.Stdout, r)
+		case "sarif":
+			err = writeSARIF(os.Stdout, args[0], r)
+		}
+		if err != nil {
+			log.Fatalf("failed to write report: %s\n", err)
+		}
+		if !r.OK() {
+			os.Exit(1)
+		}
 	},
 }
 
+// printReport lists the templates and the steps of their proofs.
+func printReport(r *verify.Report) {
+	for _, tmpl := range r.Templates {
+		fmt.Printf("%s: %s\n", tmpl.Name, tmpl.Statement)
+		for _, prf := range tmpl.Proofs {
+			printProof(prf)
+		}
+	}
+}
+
+func printProof(prf verify.Proof) {
+	for _, lemma := range prf.Preamble {
+		printSteps(lemma.Steps)
+	}
+	fmt.Printf("proof:\n")
+	printSteps(prf.Steps)
+	for _, sub := range prf.Subproofs {
+		fmt.Printf("%s: %s\n", sub.Label, sub.Goal)
+		printProof(sub.Proof)
+	}
+	if prf.QED.Outcome == verify.Proven {
+		fmt.Println("qed")
+	}
+}
+
+func printSteps(steps []verify.Step) {
+	for _, step := range steps {
+		fmt.Printf("\t%s\n", step.Expr)
+	}
+}
+
 func Execute() {
 	err := rootCmd.Execute()
 	if err != nil {
@@ -36,4 +113,8 @@ func Execute() {
 
 func init() {
 	rootCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+	rootCmd.Flags().StringVar(&format, "format", "text",
+		"output format: text, json or sarif")
+	rootCmd.PersistentFlags().StringSliceVarP(&path, "path", "I", nil,
+		"directories in which to seek imported modules")
 }
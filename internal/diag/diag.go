@@ -0,0 +1,183 @@
+// Package diag describes the problems found in i2 source, along with the
+// spans of source they concern.
+package diag
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Pos is a position in the source. Offset counts runes from the start of the
+// input; Line and Column are 1-based and count lines and runes respectively.
+type Pos struct {
+	Offset int `json:"offset"`
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+func (p Pos) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+// Span is the half-open range of source from Start up to End.
+type Span struct {
+	Start Pos `json:"start"`
+	End   Pos `json:"end"`
+}
+
+// Extent returns the Span itself, so that nodes embedding a Span satisfy
+// interfaces requiring one.
+func (s Span) Extent() Span {
+	return s
+}
+
+// IsValid indicates whether the Span refers to actual source.
+func (s Span) IsValid() bool {
+	return s.Start.Line > 0
+}
+
+// Join returns the smallest Span covering both a and b.
+func Join(a, b Span) Span {
+	switch {
+	case !a.IsValid():
+		return b
+	case !b.IsValid():
+		return a
+	}
+	s := a
+	if b.Start.Offset < s.Start.Offset {
+		s.Start = b.Start
+	}
+	if b.End.Offset > s.End.Offset {
+		s.End = b.End
+	}
+	return s
+}
+
+func (s Span) String() string {
+	return s.Start.String()
+}
+
+type Severity int
+
+const (
+	Error Severity = iota
+	Warning
+	Info
+)
+
+func (s Severity) String() string {
+	switch s {
+	case Error:
+		return "error"
+	case Warning:
+		return "warning"
+	case Info:
+		return "info"
+	default:
+		panic(fmt.Sprintf("unknown severity %d", s))
+	}
+}
+
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// Code classifies a Diagnostic.
+type Code string
+
+const (
+	Lexical   Code = "lexical"
+	Syntax    Code = "syntax"
+	Analysis  Code = "analysis"
+	Proof     Code = "proof"
+	Unsound   Code = "unsound"
+	Preamble  Code = "preamble"
+	Burden    Code = "burden"
+	QED       Code = "qed"
+	Undecided Code = "undecided"
+	Import    Code = "import"
+	Lint      Code = "lint"
+)
+
+// Diagnostic is a problem found in the source. It implements error so that
+// it can be returned through the analysis of an expression and recovered with
+// From.
+type Diagnostic struct {
+	Severity Severity `json:"severity"`
+	Code     Code     `json:"code"`
+	Span     Span     `json:"span"`
+	Message  string   `json:"message"`
+	Notes    []string `json:"notes,omitempty"`
+}
+
+func (d *Diagnostic) Error() string {
+	return d.Message
+}
+
+// Errorf returns an error-severity Diagnostic as an error.
+func Errorf(code Code, s Span, format string, a ...any) error {
+	return &Diagnostic{
+		Severity: Error,
+		Code:     code,
+		Span:     s,
+		Message:  fmt.Sprintf(format, a...),
+	}
+}
+
+// From converts err into a Diagnostic. The span and code of a Diagnostic
+// wrapped by err take precedence over the given ones; the message is always
+// that of err as a whole.
+func From(err error, code Code, s Span) Diagnostic {
+	d := Diagnostic{Severity: Error, Code: code, Span: s, Message: err.Error()}
+	var inner *Diagnostic
+	if errors.As(err, &inner) {
+		d.Code = inner.Code
+		if inner.Span.IsValid() {
+			d.Span = inner.Span
+		}
+		d.Notes = inner.Notes
+	}
+	return d
+}
+
+// HasErrors indicates whether any of diags is of error severity.
+func HasErrors(diags []Diagnostic) bool {
+	for _, d := range diags {
+		if d.Severity == Error {
+			return true
+		}
+	}
+	return false
+}
+
+// Fprint writes diags to w, quoting the line of source each refers to.
+func Fprint(w io.Writer, source string, diags []Diagnostic) {
+	lines := strings.Split(source, "\n")
+	for _, d := range diags {
+		if d.Span.IsValid() && d.Span.Start.Line <= len(lines) {
+			line := []rune(lines[d.Span.Start.Line-1])
+			fmt.Fprintf(w, ">>> %s\n    %s^\n",
+				string(line), indent(line, d.Span.Start.Column-1))
+		}
+		fmt.Fprintf(w, "%s: %s: %s [%s]\n", d.Span, d.Severity, d.Message, d.Code)
+		for _, note := range d.Notes {
+			fmt.Fprintf(w, "\t%s\n", strings.ReplaceAll(note, "\n", "\n\t"))
+		}
+	}
+}
+
+// indent returns whitespace as wide as the first n runes of line.
+func indent(line []rune, n int) string {
+	var b strings.Builder
+	for i := 0; i < n && i < len(line); i++ {
+		if line[i] == '\t' {
+			b.WriteRune('\t')
+		} else {
+			b.WriteRune(' ')
+		}
+	}
+	return b.String()
+}
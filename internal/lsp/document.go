@@ -0,0 +1,144 @@
+package lsp
+
+import (
+	"net/url"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+
+	"git.sr.ht/~lbnz/i2/internal/diag"
+	"git.sr.ht/~lbnz/i2/verify"
+)
+
+// document is an open text document along with the report of its last
+// verification.
+type document struct {
+	uri   string
+	text  []rune
+	lines []int // the offsets at which each line begins
+	r     *verify.Report
+}
+
+// file returns the path of the document if it is a file.
+func (d *document) file() string {
+	u, err := url.Parse(d.uri)
+	if err != nil || u.Scheme != "file" {
+		return ""
+	}
+	return filepath.FromSlash(u.Path)
+}
+
+func (d *document) setText(text string) {
+	d.text = []rune(text)
+	d.lines = []int{0}
+	for i, c := range d.text {
+		if c == '\n' {
+			d.lines = append(d.lines, i+1)
+		}
+	}
+}
+
+// line returns the runes of the nth line (from 0), without its newline.
+func (d *document) line(n int) []rune {
+	if n < 0 || n >= len(d.lines) {
+		return nil
+	}
+	end := len(d.text)
+	if n+1 < len(d.lines) {
+		end = d.lines[n+1] - 1
+	}
+	return d.text[d.lines[n]:end]
+}
+
+// offset converts p, whose character counts UTF-16 code units, to an offset
+// into the text.
+func (d *document) offset(p Position) int {
+	if p.Line >= len(d.lines) {
+		return len(d.text)
+	}
+	line, units, col := d.line(p.Line), 0, 0
+	for col < len(line) && units < p.Character {
+		units += utf16.RuneLen(line[col])
+		col++
+	}
+	return d.lines[p.Line] + col
+}
+
+// position converts a position in the source to one counting UTF-16 code
+// units.
+func (d *document) position(p diag.Pos) Position {
+	line := d.line(p.Line - 1)
+	units := 0
+	for i := 0; i < p.Column-1 && i < len(line); i++ {
+		units += utf16.RuneLen(line[i])
+	}
+	return Position{Line: p.Line - 1, Character: units}
+}
+
+// pos returns the source position of offset.
+func (d *document) pos(offset int) diag.Pos {
+	n := 0
+	for n+1 < len(d.lines) && d.lines[n+1] <= offset {
+		n++
+	}
+	return diag.Pos{Offset: offset, Line: n + 1, Column: offset - d.lines[n] + 1}
+}
+
+func (d *document) rangeOf(s diag.Span) Range {
+	return Range{Start: d.position(s.Start), End: d.position(s.End)}
+}
+
+func isIdentifier(c rune) bool {
+	return unicode.IsLetter(c) || unicode.IsDigit(c) || c == '_'
+}
+
+// word returns the identifier at or immediately before offset, along with
+// the offset at which it begins. Like the lexer, it takes a dot between
+// identifiers to join the parts of a qualified name.
+func (d *document) word(offset int) (string, int) {
+	joins := func(i int) bool {
+		return d.text[i] == '.' && i > 0 && isIdentifier(d.text[i-1]) &&
+			i+1 < len(d.text) && isIdentifier(d.text[i+1])
+	}
+	start, end := offset, offset
+	for start > 0 && (isIdentifier(d.text[start-1]) || joins(start-1)) {
+		start--
+	}
+	for end < len(d.text) && (isIdentifier(d.text[end]) || joins(end)) {
+		end++
+	}
+	return string(d.text[start:end]), start
+}
+
+// inJustification indicates whether offset lies within the braces of a
+// justification, i.e. ones opened immediately after a connective.
+func (d *document) inJustification(offset int) bool {
+	depth := 0
+	i := offset - 1
+	for ; i >= 0; i-- {
+		switch d.text[i] {
+		case '}':
+			depth++
+		case '{':
+			depth--
+		case ';':
+			if depth == 0 {
+				return false
+			}
+		}
+		if depth < 0 {
+			break
+		}
+	}
+	if i < 0 {
+		return false
+	}
+	before := strings.TrimRightFunc(string(d.text[:i]), unicode.IsSpace)
+	for _, conn := range []string{"===", "==>", "<=="} {
+		if strings.HasSuffix(before, conn) {
+			return true
+		}
+	}
+	return false
+}
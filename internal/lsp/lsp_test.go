@@ -0,0 +1,221 @@
+package lsp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const source = `@func p(x any) bool;
+@tmpl ax(x any) { p(x) };
+tmpl thm() { p(1) } {
+	true
+==> { ax(1) }
+	p(1);
+};
+tmpl bad() { p(1) } {
+	true
+==>	p(1);
+};`
+
+// session runs the server over the given messages, returning what it writes
+// keyed by request ID, or by method for notifications.
+func session(t *testing.T, msgs ...string) map[string]json.RawMessage {
+	var in bytes.Buffer
+	for _, m := range msgs {
+		fmt.Fprintf(&in, "Content-Length: %d\r\n\r\n%s", len(m), m)
+	}
+	var out bytes.Buffer
+	if err := Serve(context.Background(), &in, &out, nil); err != nil {
+		t.Fatal(err)
+	}
+	replies := map[string]json.RawMessage{}
+	c := newConn(&out, nil)
+	for {
+		body, err := c.read()
+		if err == io.EOF {
+			return replies
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		var msg struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+			Result json.RawMessage `json:"result"`
+			Params json.RawMessage `json:"params"`
+		}
+		if err := json.Unmarshal(body, &msg); err != nil {
+			t.Fatal(err)
+		}
+		if msg.Method != "" {
+			replies[msg.Method] = msg.Params
+		} else {
+			replies[string(msg.ID)] = msg.Result
+		}
+	}
+}
+
+func call(id int, method string, params any) string {
+	b, _ := json.Marshal(map[string]any{
+		"jsonrpc": "2.0", "id": id, "method": method, "params": params,
+	})
+	return string(b)
+}
+
+func notify(method string, params any) string {
+	b, _ := json.Marshal(map[string]any{
+		"jsonrpc": "2.0", "method": method, "params": params,
+	})
+	return string(b)
+}
+
+func at(line, char int) map[string]any {
+	return map[string]any{
+		"textDocument": map[string]string{"uri": "file:///a.i2"},
+		"position":     Position{line, char},
+	}
+}
+
+func TestServer(t *testing.T) {
+	doc := map[string]any{"uri": "file:///a.i2", "text": source}
+	replies := session(t,
+		call(1, "initialize", map[string]any{}),
+		notify("initialized", map[string]any{}),
+		notify("textDocument/didOpen", map[string]any{"textDocument": doc}),
+		call(2, "textDocument/definition", at(4, 7)),
+		call(3, "textDocument/hover", at(4, 7)),
+		call(4, "textDocument/completion", at(4, 6)),
+		call(5, "textDocument/completion", at(3, 1)),
+		call(6, "textDocument/documentSymbol", map[string]any{
+			"textDocument": map[string]string{"uri": "file:///a.i2"},
+		}),
+		call(7, "shutdown", nil),
+		notify("exit", nil),
+	)
+
+	var diags PublishDiagnosticsParams
+	if err := json.Unmarshal(replies["textDocument/publishDiagnostics"], &diags); err != nil {
+		t.Fatal(err)
+	}
+	if len(diags.Diagnostics) != 1 || diags.Diagnostics[0].Range.Start.Line != 8 {
+		t.Fatalf("unexpected diagnostics %+v", diags)
+	}
+
+	var loc Location
+	if err := json.Unmarshal(replies["2"], &loc); err != nil {
+		t.Fatal(err)
+	}
+	if loc.Range.Start != (Position{1, 0}) {
+		t.Fatalf("definition at %+v", loc.Range)
+	}
+
+	var hover Hover
+	if err := json.Unmarshal(replies["3"], &hover); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(hover.Contents.Value, "@tmpl ax(x any) { p(x) }") {
+		t.Fatalf("unexpected hover %q", hover.Contents.Value)
+	}
+
+	var items []CompletionItem
+	if err := json.Unmarshal(replies["4"], &items); err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 3 || items[0].Label != "ax" {
+		t.Fatalf("unexpected completion %+v", items)
+	}
+	if err := json.Unmarshal(replies["5"], &items); err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 0 {
+		t.Fatalf("completion outside justification %+v", items)
+	}
+
+	var syms []DocumentSymbol
+	if err := json.Unmarshal(replies["6"], &syms); err != nil {
+		t.Fatal(err)
+	}
+	if len(syms) != 3 || syms[1].Name != "thm" || syms[1].Detail != "theorem" {
+		t.Fatalf("unexpected symbols %+v", syms)
+	}
+}
+
+func TestImports(t *testing.T) {
+	dir := t.TempDir()
+	module := `mod m;
+export @func q(x any) bool;
+export @tmpl qx(x any) { q(x) ==> q(x) };`
+	err := os.WriteFile(filepath.Join(dir, "m.i2"), []byte(module), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.ToSlash(filepath.Join(dir, "a.i2"))
+	uri := (&url.URL{Scheme: "file", Path: file}).String()
+	text := `import "m";
+tmpl t() { q(1) ==> q(1) } {
+	q(1) ==> { m.qx(1) } q(1);
+};`
+	pos := func(line, char int) map[string]any {
+		return map[string]any{
+			"textDocument": map[string]string{"uri": uri},
+			"position":     Position{line, char},
+		}
+	}
+	replies := session(t,
+		call(1, "initialize", map[string]any{}),
+		notify("textDocument/didOpen", map[string]any{
+			"textDocument": map[string]any{"uri": uri, "text": text},
+		}),
+		call(2, "textDocument/definition", pos(2, 15)),
+		call(3, "textDocument/hover", pos(2, 15)),
+		call(4, "textDocument/hover", pos(1, 12)),
+		call(5, "textDocument/completion", pos(2, 12)),
+		call(6, "shutdown", nil),
+		notify("exit", nil),
+	)
+
+	var loc Location
+	if err := json.Unmarshal(replies["2"], &loc); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(loc.URI, "/m.i2") ||
+		loc.Range.Start != (Position{2, 0}) {
+		t.Fatalf("definition at %+v", loc)
+	}
+
+	var hover Hover
+	if err := json.Unmarshal(replies["3"], &hover); err != nil {
+		t.Fatal(err)
+	}
+	want := "@tmpl qx(x any) { q(x) ==> q(x) }\n"
+	if !strings.Contains(hover.Contents.Value, want) ||
+		!strings.Contains(hover.Contents.Value, "imported from `m.i2`") {
+		t.Fatalf("unexpected hover %q", hover.Contents.Value)
+	}
+	if err := json.Unmarshal(replies["4"], &hover); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(hover.Contents.Value, "@func q(x any) bool") {
+		t.Fatalf("unexpected hover %q", hover.Contents.Value)
+	}
+
+	var items []CompletionItem
+	if err := json.Unmarshal(replies["5"], &items); err != nil {
+		t.Fatal(err)
+	}
+	var labels []string
+	for _, item := range items {
+		labels = append(labels, item.Label)
+	}
+	if strings.Join(labels, " ") != "t m.qx qx" {
+		t.Fatalf("unexpected completion %v", labels)
+	}
+}
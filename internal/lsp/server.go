@@ -0,0 +1,409 @@
+// Package lsp implements a Language Server Protocol server for i2, serving
+// diagnostics from the verifier along with navigation and completion of the
+// symbols it declares and imports.
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"git.sr.ht/~lbnz/i2/internal/diag"
+	"git.sr.ht/~lbnz/i2/verify"
+)
+
+// Server serves a single client.
+type Server struct {
+	ctx      context.Context
+	importer *verify.Importer
+	conn     *conn
+	docs     map[string]*document
+	shutdown bool
+}
+
+// Serve serves the client communicating over r and w until it exits or ctx
+// ends, resolving imports with importer.
+func Serve(ctx context.Context, r io.Reader, w io.Writer,
+	importer *verify.Importer) error {
+	s := &Server{
+		ctx:      ctx,
+		importer: importer,
+		conn:     newConn(r, w),
+		docs:     map[string]*document{},
+	}
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		body, err := s.conn.read()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		var req request
+		if err := json.Unmarshal(body, &req); err != nil {
+			if err := s.reply(nil, nil, &responseError{
+				codeParseError, err.Error(),
+			}); err != nil {
+				return err
+			}
+			continue
+		}
+		if req.Method == "exit" {
+			return nil
+		}
+		result, err := s.handle(req)
+		if req.ID == nil {
+			continue
+		}
+		var rerr *responseError
+		if err != nil && !errors.As(err, &rerr) {
+			rerr = &responseError{codeInvalidParams, err.Error()}
+		}
+		if err := s.reply(req.ID, result, rerr); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *Server) reply(id *json.RawMessage, result any, err *responseError) error {
+	if err != nil {
+		result = nil
+	}
+	return s.conn.write(response{
+		JSONRPC: "2.0", ID: id, Result: result, Error: err,
+	})
+}
+
+func (s *Server) notify(method string, params any) error {
+	return s.conn.write(notification{
+		JSONRPC: "2.0", Method: method, Params: params,
+	})
+}
+
+// handle dispatches req, returning the result of a request.
+func (s *Server) handle(req request) (any, error) {
+	if s.shutdown && req.Method != "exit" {
+		return nil, &responseError{codeInvalidRequest, "server is shut down"}
+	}
+	switch req.Method {
+	case "initialize":
+		return s.initialize(), nil
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+	case "textDocument/didOpen":
+		var p DidOpenTextDocumentParams
+		if err := json.Unmarshal(req.Params, &p); err != nil {
+			return nil, err
+		}
+		d := &document{uri: p.TextDocument.URI}
+		d.setText(p.TextDocument.Text)
+		s.docs[d.uri] = d
+		return nil, s.verify(d)
+	case "textDocument/didChange":
+		var p DidChangeTextDocumentParams
+		if err := json.Unmarshal(req.Params, &p); err != nil {
+			return nil, err
+		}
+		d, ok := s.docs[p.TextDocument.URI]
+		if !ok || len(p.ContentChanges) == 0 {
+			return nil, nil
+		}
+		d.setText(p.ContentChanges[len(p.ContentChanges)-1].Text)
+		return nil, nil
+	case "textDocument/didSave":
+		var p DidSaveTextDocumentParams
+		if err := json.Unmarshal(req.Params, &p); err != nil {
+			return nil, err
+		}
+		d, ok := s.docs[p.TextDocument.URI]
+		if !ok {
+			return nil, nil
+		}
+		if p.Text != nil {
+			d.setText(*p.Text)
+		}
+		return nil, s.verify(d)
+	case "textDocument/didClose":
+		var p DidCloseTextDocumentParams
+		if err := json.Unmarshal(req.Params, &p); err != nil {
+			return nil, err
+		}
+		delete(s.docs, p.TextDocument.URI)
+		return nil, s.notify("textDocument/publishDiagnostics",
+			PublishDiagnosticsParams{p.TextDocument.URI, []Diagnostic{}})
+	case "textDocument/definition":
+		return s.positional(req, (*Server).definition)
+	case "textDocument/hover":
+		return s.positional(req, (*Server).hover)
+	case "textDocument/completion":
+		return s.positional(req, (*Server).completion)
+	case "textDocument/documentSymbol":
+		var p DocumentSymbolParams
+		if err := json.Unmarshal(req.Params, &p); err != nil {
+			return nil, err
+		}
+		d, ok := s.docs[p.TextDocument.URI]
+		if !ok {
+			return []DocumentSymbol{}, nil
+		}
+		return documentSymbols(d), nil
+	default:
+		if req.ID == nil {
+			// notifications we do not understand may be ignored
+			return nil, nil
+		}
+		return nil, &responseError{
+			codeMethodNotFound, fmt.Sprintf("unknown method %q", req.Method),
+		}
+	}
+}
+
+func (s *Server) initialize() InitializeResult {
+	var res InitializeResult
+	caps := &res.Capabilities
+	caps.TextDocumentSync.OpenClose = true
+	caps.TextDocumentSync.Change = SyncFull
+	caps.DefinitionProvider = true
+	caps.HoverProvider = true
+	caps.CompletionProvider.TriggerCharacters = []string{"{"}
+	caps.DocumentSymbolProvider = true
+	res.ServerInfo.Name = "i2"
+	return res
+}
+
+// verify verifies d and publishes its diagnostics.
+func (s *Server) verify(d *document) error {
+	r, err := verify.Verify(s.ctx, string(d.text), verify.Options{
+		File:     d.file(),
+		Importer: s.importer,
+	})
+	if err != nil {
+		return err
+	}
+	d.r = r
+	diags := make([]Diagnostic, len(r.Diagnostics))
+	for i, dg := range r.Diagnostics {
+		diags[i] = Diagnostic{
+			Range:    d.rangeOf(dg.Span),
+			Severity: severity(dg.Severity),
+			Code:     string(dg.Code),
+			Source:   "i2",
+			Message:  strings.Join(append([]string{dg.Message}, dg.Notes...), "\n"),
+		}
+	}
+	return s.notify("textDocument/publishDiagnostics",
+		PublishDiagnosticsParams{d.uri, diags})
+}
+
+func severity(s diag.Severity) DiagnosticSeverity {
+	switch s {
+	case diag.Error:
+		return SeverityError
+	case diag.Warning:
+		return SeverityWarning
+	default:
+		return SeverityInformation
+	}
+}
+
+// positional unmarshals the parameters of a request concerning a position in
+// a document before passing them to f.
+func (s *Server) positional(req request,
+	f func(*Server, *document, int) any) (any, error) {
+	var p TextDocumentPositionParams
+	if err := json.Unmarshal(req.Params, &p); err != nil {
+		return nil, err
+	}
+	d, ok := s.docs[p.TextDocument.URI]
+	if !ok || d.r == nil {
+		return nil, nil
+	}
+	return f(s, d, d.offset(p.Position)), nil
+}
+
+func (s *Server) definition(d *document, offset int) any {
+	name, _ := d.word(offset)
+	decl, ok := lookup(d.r, name)
+	if !ok {
+		return nil
+	}
+	if decl.file == "" {
+		return Location{URI: d.uri, Range: d.rangeOf(decl.span)}
+	}
+	src := s.open(decl.file)
+	if src == nil {
+		return nil
+	}
+	return Location{URI: src.uri, Range: src.rangeOf(decl.span)}
+}
+
+// open returns the document of file, which is read unless it is open.
+func (s *Server) open(file string) *document {
+	for _, d := range s.docs {
+		if d.file() == file {
+			return d
+		}
+	}
+	text, err := os.ReadFile(file)
+	if err != nil {
+		return nil
+	}
+	u := url.URL{Scheme: "file", Path: filepath.ToSlash(file)}
+	d := &document{uri: u.String()}
+	d.setText(string(text))
+	return d
+}
+
+// declaration is the statement declaring a symbol, at span in the document
+// or, if file is set, in that of the module the symbol is imported from.
+type declaration struct {
+	source string
+	file   string
+	span   diag.Span
+}
+
+// lookup returns the declaration of name in r, or else that of the symbol
+// imported as name.
+func lookup(r *verify.Report, name string) (declaration, bool) {
+	for _, tmpl := range r.Templates {
+		if tmpl.Name == name {
+			return declaration{templateSource(tmpl), "", tmpl.Span}, true
+		}
+	}
+	for _, f := range r.Functions {
+		if f.Name == name {
+			return declaration{functionSource(f), "", f.Span}, true
+		}
+	}
+	for _, t := range r.Terms {
+		if t.Name == name {
+			return declaration{termSource(t), "", t.Span}, true
+		}
+	}
+	for _, imp := range r.Imports {
+		if imp.Name != name {
+			continue
+		}
+		switch {
+		case imp.Template != nil:
+			tmpl := *imp.Template
+			return declaration{templateSource(tmpl), imp.File, tmpl.Span}, true
+		case imp.Function != nil:
+			f := *imp.Function
+			return declaration{functionSource(f), imp.File, f.Span}, true
+		case imp.Term != nil:
+			t := *imp.Term
+			return declaration{termSource(t), imp.File, t.Span}, true
+		}
+	}
+	return declaration{}, false
+}
+
+func templateSource(tmpl verify.Template) string {
+	return fmt.Sprintf("%stmpl %s(%s) { %s }", axiom(tmpl.IsAxiom),
+		tmpl.Name, params(tmpl.Params), tmpl.Assertion)
+}
+
+func functionSource(f verify.Function) string {
+	return fmt.Sprintf("%sfunc %s(%s) %s", axiom(f.IsAxiom), f.Name,
+		params(f.Params), f.Return)
+}
+
+func termSource(t verify.Term) string {
+	return fmt.Sprintf("term %s %s", t.Name, t.Type)
+}
+
+func (s *Server) hover(d *document, offset int) any {
+	name, start := d.word(offset)
+	decl, ok := lookup(d.r, name)
+	if !ok {
+		return nil
+	}
+	text := fmt.Sprintf("```i2\n%s\n```", decl.source)
+	if decl.file != "" {
+		text += fmt.Sprintf("\n\nimported from `%s`",
+			filepath.Base(decl.file))
+	}
+	rng := Range{
+		Start: d.position(d.pos(start)),
+		End:   d.position(d.pos(start + len([]rune(name)))),
+	}
+	return Hover{
+		Contents: MarkupContent{Kind: "markdown", Value: text},
+		Range:    &rng,
+	}
+}
+
+func axiom(is bool) string {
+	if is {
+		return "@"
+	}
+	return ""
+}
+
+func params(arr []verify.Parameter) string {
+	s := make([]string, len(arr))
+	for i, p := range arr {
+		s[i] = fmt.Sprintf("%s %s", p.Name, p.Type)
+	}
+	return strings.Join(s, ", ")
+}
+
+// completion offers the templates that may be cited at offset, those
+// declared in d before those imported.
+func (s *Server) completion(d *document, offset int) any {
+	items := []CompletionItem{}
+	if !d.inJustification(offset) {
+		return items
+	}
+	for _, tmpl := range d.r.Templates {
+		items = append(items, templateItem(tmpl.Name, tmpl))
+	}
+	for _, imp := range d.r.Imports {
+		if imp.Template != nil {
+			items = append(items, templateItem(imp.Name, *imp.Template))
+		}
+	}
+	return items
+}
+
+func templateItem(name string, tmpl verify.Template) CompletionItem {
+	return CompletionItem{
+		Label:  name,
+		Kind:   CompletionFunction,
+		Detail: fmt.Sprintf("(%s) { %s }", params(tmpl.Params), tmpl.Assertion),
+	}
+}
+
+// documentSymbols lists the templates of d, axioms and theorems alike.
+func documentSymbols(d *document) []DocumentSymbol {
+	syms := []DocumentSymbol{}
+	if d.r == nil {
+		return syms
+	}
+	for _, tmpl := range d.r.Templates {
+		detail := "theorem"
+		if tmpl.IsAxiom {
+			detail = "axiom"
+		}
+		rng := d.rangeOf(tmpl.Span)
+		syms = append(syms, DocumentSymbol{
+			Name:           tmpl.Name,
+			Detail:         detail,
+			Kind:           SymbolFunction,
+			Range:          rng,
+			SelectionRange: rng,
+		})
+	}
+	return syms
+}
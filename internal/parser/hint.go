@@ -0,0 +1,110 @@
+package parser
+
+import (
+	"sort"
+	"strings"
+
+	"git.sr.ht/~lbnz/i2/internal/symbol"
+)
+
+// maxInstances bounds the instances of each template tried as a hint.
+const maxInstances = 256
+
+// Hint is a citation that justifies a step.
+type Hint struct {
+	Step     symbol.JustifiableBinaryOpExpr
+	Citation symbol.Expr
+	Size     int
+}
+
+// hintSteps seeks citations for the steps on the line sought that are
+// unjustified or fail, from the templates and proofs of tbl.
+func (l *lexer) hintSteps(steps []Step, tbl symbol.Table) {
+	for _, step := range steps {
+		s := step.Expr.Extent()
+		if s.Start.Line > l.hintLine || s.End.Line < l.hintLine {
+			continue
+		}
+		if step.Outcome == Proven && step.Expr.Just != nil {
+			continue
+		}
+		l.hints = append(l.hints, l.hint(step.Expr, tbl)...)
+	}
+}
+
+// hint returns the citations that, added to those of step, make it hold,
+// the smallest first.
+func (l *lexer) hint(step symbol.JustifiableBinaryOpExpr, tbl symbol.Table) []Hint {
+	base := step.Just
+	if _, err := step.Justification(tbl); base != nil && err != nil {
+		base = nil
+	}
+	var hints []Hint
+	for _, c := range citations(symbol.Terms(step), tbl) {
+		just := append(append(symbol.Citations{}, base...), c.expr)
+		if l.holds(step.BinaryOpExpr, just, tbl) {
+			hints = append(hints, Hint{
+				Step: step, Citation: c.expr, Size: symbol.Size(c.expr),
+			})
+		}
+	}
+	sort.Slice(hints, func(i, j int) bool {
+		if hints[i].Size != hints[j].Size {
+			return hints[i].Size < hints[j].Size
+		}
+		return hints[i].Citation.String() < hints[j].Citation.String()
+	})
+	return hints
+}
+
+// citation is a citation that may justify a step, and what it asserts.
+type citation struct {
+	expr      symbol.Expr
+	assertion symbol.Expr
+}
+
+// citations returns what may be cited in tbl, with templates instantiated
+// with terms: the templates other than that being proven, and the labelled
+// proofs.
+func citations(terms []symbol.Expr, tbl symbol.Table) []citation {
+	self := ""
+	if this, ok := tbl["this"].(symbol.Template); ok {
+		self = this.Name
+	}
+	var cs []citation
+	for name, sym := range tbl {
+		switch sym := sym.(type) {
+		case symbol.Template:
+			if name == "this" || sym.Name == self ||
+				shadowsImport(name, sym, tbl) {
+				continue
+			}
+			for _, inst := range symbol.Instances(
+				name, sym, terms, tbl, maxInstances,
+			) {
+				cs = append(cs, citation{inst, sym.Substitute(inst.Args)})
+			}
+		case symbol.LocalProof:
+			cs = append(cs, citation{symbol.SimpleExpr{Name: name}, sym.Expr})
+		}
+	}
+	sort.Slice(cs, func(i, j int) bool {
+		si, sj := symbol.Size(cs[i].expr), symbol.Size(cs[j].expr)
+		if si != sj {
+			return si < sj
+		}
+		return cs[i].expr.String() < cs[j].expr.String()
+	})
+	return cs
+}
+
+// shadowsImport indicates whether name is the qualified name of an imported
+// template that is also bound to its unqualified name.
+func shadowsImport(name string, tmpl symbol.Template, tbl symbol.Table) bool {
+	i := strings.LastIndex(name, ".")
+	if i < 0 {
+		return false
+	}
+	other, ok := tbl[name[i+1:]].(symbol.Template)
+	return ok && other.Name == tmpl.Name && other.Span == tmpl.Span
+}
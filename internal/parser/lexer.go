@@ -1,10 +1,16 @@
 package parser
 
 import (
+	"context"
 	"fmt"
-	"os"
+	"sort"
 	"strings"
+	"time"
 	"unicode"
+
+	"git.sr.ht/~lbnz/i2/internal/diag"
+	"git.sr.ht/~lbnz/i2/internal/symbol"
+	"git.sr.ht/~lbnz/i2/internal/truth"
 )
 
 const (
@@ -15,74 +21,151 @@ const (
 type lexer struct {
 	input []rune
 	pos   int
+	start int   // the position at which the last token began
+	lines []int // the positions at which each line begins
+	diags []diag.Diagnostic
+
+	// sigma is the table of the symbols declared so far.
+	sigma symbol.Table
+
+	// mode, if nonzero, is the token emitted before the input.
+	mode       int
+	evaluation *Evaluation
+
+	*verifier
+	functions []symbol.Function
+	terms     []Term
+	templates []TemplateResult
+
+	// module is the name declared by a `mod' statement, and exports the
+	// symbols declared for export.
+	module  string
+	exports symbol.Table
+
+	importer Importer
+	// chain is the chain of files importing one another, ending with that
+	// being lexed, if known.
+	chain []string
+	// imported maps the unqualified names bound by imports to the files
+	// of the modules they were imported from, and imports records every
+	// name so bound.
+	imported map[string]string
+	imports  []Import
+	// deps records the modification times of the files verified.
+	deps map[string]time.Time
+	// declared indicates whether any symbol has been bound.
+	declared bool
+
+	// linter, if not nil, lints the proofs as they are verified.
+	linter *linter
+
+	// hintLine, if nonzero, is the line of the steps for which hints are
+	// sought.
+	hintLine int
+	hints    []Hint
+
+	// search, if not nil, configures the search for chains filling the
+	// gaps of failing steps.
+	search *Search
+	fills  []Fill
+
+	// smtlib, if true, translates the steps verified into Scripts. steps
+	// counts those of the template being verified.
+	smtlib  bool
+	steps   int
+	scripts []Script
 }
 
-type lineinfo struct {
-	lines   [][]rune
-	linepos int // the position on the line
-	n       int // the line number
+func (l *lexer) result() *Result {
+	return &Result{
+		Functions:   l.functions,
+		Terms:       l.terms,
+		Templates:   l.templates,
+		Imports:     l.imports,
+		Diagnostics: l.diags,
+		Hints:       l.hints,
+		Fills:       l.fills,
+		Scripts:     l.scripts,
+	}
 }
 
-func getlineinfo(input []rune, pos int) *lineinfo {
-	info := &lineinfo{lines: [][]rune{}}
-	last := 0
-	for i := 0; i < len(input); i++ {
-		if input[i] == '\n' {
-			if last <= pos && pos <= i {
-				info.linepos = pos - last
-				info.n = len(info.lines)
-			}
-			info.lines = append(info.lines, input[last:i])
-			last = i + 1
+// builtins are the symbols declared before any input.
+var builtins = symbol.Table{"1": symbol.Any}
+
+func newLexer(input string) *lexer {
+	l := &lexer{
+		input:    []rune(input),
+		lines:    []int{0},
+		sigma:    symbol.Table{}.Nest(builtins),
+		exports:  symbol.Table{},
+		imported: map[string]string{},
+		deps:     map[string]time.Time{},
+		verifier: &verifier{context.Background(), truth.DefaultTimeout},
+	}
+	for i, c := range l.input {
+		if c == '\n' {
+			l.lines = append(l.lines, i+1)
 		}
 	}
-	return info
+	return l
+}
+
+func (l *lexer) position(pos int) diag.Pos {
+	n := sort.Search(len(l.lines), func(i int) bool {
+		return l.lines[i] > pos
+	}) - 1
+	return diag.Pos{Offset: pos, Line: n + 1, Column: pos - l.lines[n] + 1}
 }
 
-type colour string
+func (l *lexer) span(start, end int) diag.Span {
+	return diag.Span{Start: l.position(start), End: l.position(end)}
+}
 
-const (
-	colourRed colour = "\x1B[31m"
-	colourGrn        = "\x1B[32m"
-	colourYel        = "\x1B[33m"
-	colourBlu        = "\x1B[34m"
-	colourMag        = "\x1B[35m"
-	colourCyn        = "\x1B[36m"
-	colourWht        = "\x1B[37m"
-	colourOff        = "\x1B[0m"
-)
+func (l *lexer) report(d diag.Diagnostic) {
+	l.diags = append(l.diags, d)
+}
 
+func (l *lexer) errorf(code diag.Code, s diag.Span, format string, a ...any) {
+	l.report(diag.Diagnostic{
+		Severity: diag.Error,
+		Code:     code,
+		Span:     s,
+		Message:  fmt.Sprintf(format, a...),
+	})
+}
+
+// Error reports a syntax error at the last token lexed.
 func (l *lexer) Error(err string) {
-	info := getlineinfo(l.input, l.pos)
-	//fmt.Fprint(os.Stderr, colourRed)
-	fmt.Fprintf(os.Stderr, ">>> %s\n    ", string(info.lines[info.n]))
-	//fmt.Fprint(os.Stderr, colourOff)
-	for i := 0; i < info.linepos; i++ {
-		fmt.Fprintf(os.Stderr, " ")
-	}
-	fmt.Fprintf(os.Stderr, "^\n")
-	fmt.Fprintf(os.Stderr, "error: %s at position %d on line %d\n",
-		err, info.linepos, info.n+1)
-	os.Exit(1)
+	l.errorf(diag.Syntax, l.span(l.start, l.pos), "%s", err)
 }
 
 func (l *lexer) Lex(lval *yySymType) int {
-	if l.pos += skipNPCs(l.input[l.pos:], l); l.pos >= len(l.input) {
-		return tkEof
-	}
 	lexers := []func([]rune, *yySymType) (*token, error){
-		lexPunct, lexString, lexNum,
+		lexPunct, lexString, lexNum, lexQuoted,
+	}
+	if mode := l.mode; mode != 0 {
+		l.mode = 0
+		return mode
 	}
-	for _, lex := range lexers {
-		tk, err := lex(l.input[l.pos:], lval)
-		if err != nil {
-			continue
+	for {
+		if l.pos += skipNPCs(l.input[l.pos:], l); l.pos >= len(l.input) {
+			l.start = len(l.input)
+			return tkEof
 		}
-		l.pos += tk.length
-		return tk.token
+		l.start = l.pos
+		for _, lex := range lexers {
+			tk, err := lex(l.input[l.pos:], lval)
+			if err != nil {
+				continue
+			}
+			l.pos += tk.length
+			lval.span = l.span(l.start, l.pos)
+			return tk.token
+		}
+		l.pos++
+		l.errorf(diag.Lexical, l.span(l.start, l.pos),
+			"unrecognised character '%c'", l.input[l.start])
 	}
-	l.Error("unrecoginized sequence")
-	return tkError
 }
 
 func skipNPCs(input []rune, l *lexer) int {
@@ -105,8 +188,9 @@ func skipComments(input []rune, l *lexer) int {
 		}
 		n++
 	}
-	l.Error("file ends in comment")
-	return -1
+	l.errorf(diag.Lexical, l.span(l.pos, len(l.input)),
+		"file ends in comment")
+	return len(input)
 }
 
 type token struct {
@@ -180,11 +264,24 @@ func lexPunct(input []rune, lval *yySymType) (*token, error) {
 }
 
 var keyword = map[string]int{
-	"tmpl":  tkTmpl,
-	"func":  tkFunc,
-	"term":  tkTerm,
-	"true":  tkTrue,
-	"false": tkFalse,
+	"tmpl":   tkTmpl,
+	"func":   tkFunc,
+	"term":   tkTerm,
+	"true":   tkTrue,
+	"false":  tkFalse,
+	"mod":    tkMod,
+	"import": tkImport,
+	"export": tkExport,
+
+	// the keywords of proof forms, which are also identifiers wherever one
+	// is expected (see the identifier rule of the grammar)
+	"induction":     tkInduction,
+	"on":            tkOn,
+	"using":         tkUsing,
+	"cases":         tkCases,
+	"contradiction": tkContradiction,
+	"assume":        tkAssume,
+	"case":          tkCase,
 }
 
 func stringtype(s string) int {
@@ -201,12 +298,41 @@ func lexString(input []rune, lval *yySymType) (*token, error) {
 	cond := func(c rune) bool {
 		return unicode.IsLetter(c) || unicode.IsDigit(c) || c == '_'
 	}
+	// a dot joins the parts of a qualified name, such as peano.succ
+	qualifies := func(n int) bool {
+		return input[n] == '.' && n+1 < len(input) &&
+			(unicode.IsLetter(input[n+1]) || input[n+1] == '_')
+	}
+	n := 0
+	for n < len(input) && (cond(input[n]) || qualifies(n)) {
+		n++
+	}
+	lval.s = string(input[:n])
+	return &token{stringtype(lval.s), n}, nil
+}
+
+func lexQuoted(input []rune, lval *yySymType) (*token, error) {
+	if input[0] != '"' {
+		return nil, fmt.Errorf("invalid string first char: '%c'", input[0])
+	}
 	var b strings.Builder
-	for n := 0; n < len(input) && cond(input[n]); n++ {
-		b.WriteRune(input[n])
+	for n := 1; n < len(input); n++ {
+		switch c := input[n]; c {
+		case '"':
+			lval.s = b.String()
+			return &token{tkString, n + 1}, nil
+		case '\\':
+			if n+1 < len(input) {
+				n++
+				b.WriteRune(input[n])
+			}
+		case '\n':
+			return nil, fmt.Errorf("newline in string")
+		default:
+			b.WriteRune(c)
+		}
 	}
-	lval.s = b.String()
-	return &token{stringtype(lval.s), len(lval.s)}, nil
+	return nil, fmt.Errorf("unterminated string")
 }
 
 func lexNum(input []rune, lval *yySymType) (*token, error) {
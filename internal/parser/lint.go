@@ -0,0 +1,135 @@
+package parser
+
+import (
+	"fmt"
+
+	"git.sr.ht/~lbnz/i2/internal/diag"
+	"git.sr.ht/~lbnz/i2/internal/symbol"
+)
+
+// linter records what the proofs of a source file cite, so that the axioms it
+// declares and never cites can be reported once it has been verified.
+type linter struct {
+	cited map[string]bool
+}
+
+func newLinter() *linter {
+	return &linter{cited: map[string]bool{}}
+}
+
+// warn reports a finding of the linter.
+func (l *lexer) warn(severity diag.Severity, s diag.Span, format string, a ...any) {
+	l.report(diag.Diagnostic{
+		Severity: severity,
+		Code:     diag.Lint,
+		Span:     s,
+		Message:  fmt.Sprintf(format, a...),
+	})
+}
+
+// lintChain reports the unnecessary citations, unused preamble proofs and
+// mergeable steps of the proof prf, verified in tbl as presult.
+func (l *lexer) lintChain(prf symbol.ProofChain, presult ProofResult,
+	tbl symbol.Table) {
+	for _, lemma := range presult.Preamble {
+		l.lintSteps(lemma.Steps, tbl)
+	}
+	l.lintSteps(presult.Steps, tbl)
+	for i, preprf := range prf.Preamble {
+		lbl := preprf.Label()
+		if lbl == "" || used(lbl, prf.Preamble[i+1:], prf.Proof) {
+			continue
+		}
+		l.warn(diag.Warning, preprf.Extent(),
+			"preamble proof `%s' is never used", lbl)
+	}
+}
+
+// used indicates whether the label lbl is mentioned or cited by any of the
+// proofs following it.
+func used(lbl string, preamble []symbol.Proof, proof symbol.Proof) bool {
+	for _, prf := range append(preamble, proof) {
+		for _, step := range prf.Chain() {
+			if step.Just.Cites(lbl) || symbol.Mentions(step.E1, lbl) ||
+				symbol.Mentions(step.E2, lbl) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// lintSteps reports the citations of steps that are unnecessary, and the
+// consecutive equivalences that hold without the step between them.
+func (l *lexer) lintSteps(steps []Step, tbl symbol.Table) {
+	for i, step := range steps {
+		for _, c := range step.Expr.Just {
+			l.linter.cited[citedName(c)] = true
+		}
+		if step.Outcome != Proven || step.Expr.Just == nil {
+			continue
+		}
+		if l.holds(step.Expr.BinaryOpExpr, nil, tbl) {
+			l.warn(diag.Warning, step.Expr.Extent(),
+				"step %d holds without its justification `%s'",
+				i+1, step.Expr.Just)
+			continue
+		}
+		if len(step.Expr.Just) == 1 {
+			continue
+		}
+		for j, c := range step.Expr.Just {
+			rest := append(symbol.Citations{}, step.Expr.Just[:j]...)
+			rest = append(rest, step.Expr.Just[j+1:]...)
+			if l.holds(step.Expr.BinaryOpExpr, rest, tbl) {
+				l.warn(diag.Warning, c.Extent(),
+					"step %d holds without citing `%s'", i+1, c)
+			}
+		}
+	}
+	for i := 0; i+1 < len(steps); i++ {
+		a, b := steps[i], steps[i+1]
+		if a.Outcome != Proven || b.Outcome != Proven ||
+			a.Expr.Op != symbol.Eqv || b.Expr.Op != symbol.Eqv {
+			continue
+		}
+		var just symbol.Citations
+		if a.Expr.Just != nil || b.Expr.Just != nil {
+			just = append(append(just, a.Expr.Just...), b.Expr.Just...)
+		}
+		merged := symbol.BinaryOpExpr{
+			Op: symbol.Eqv, E1: a.Expr.E1, E2: b.Expr.E2,
+		}
+		if l.holds(merged, just, tbl) {
+			l.warn(diag.Info, diag.Join(a.Expr.Extent(), b.Expr.Extent()),
+				"steps %d and %d may be merged into `%s'", i+1, i+2, merged)
+			i++
+		}
+	}
+}
+
+func citedName(c symbol.Expr) string {
+	switch c := c.(type) {
+	case symbol.PostfixExpr:
+		return c.Name
+	case symbol.SimpleExpr:
+		return c.Name
+	default:
+		return ""
+	}
+}
+
+// lintAxioms reports the axiom templates declared, and not exported, that no
+// proof cites.
+func (l *lexer) lintAxioms() {
+	for _, t := range l.templates {
+		tmpl := t.Template
+		if !tmpl.IsAxiom || l.linter.cited[tmpl.Name] {
+			continue
+		}
+		if _, ok := l.exports[tmpl.Name]; ok {
+			continue
+		}
+		l.warn(diag.Warning, tmpl.Span, "axiom `%s' is never cited", tmpl.Name)
+	}
+}
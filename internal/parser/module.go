@@ -0,0 +1,373 @@
+package parser
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"git.sr.ht/~lbnz/i2/internal/diag"
+	"git.sr.ht/~lbnz/i2/internal/symbol"
+)
+
+// Module is a verified file along with the symbols it exports.
+type Module struct {
+	// Name qualifies the exported symbols in importing files. It is that
+	// declared by the `mod' statement of the file, or else the name of the
+	// file without its extension.
+	Name    string
+	File    string
+	Exports symbol.Table
+	Result  *Result
+
+	// Symbols binds the qualified names of the symbols of the file, and of
+	// those it imports, to the symbols the definitions of the exports may
+	// refer to. Every symbol is named apart by its qualified name, with
+	// which its definition refers to the others.
+	Symbols symbol.Table
+
+	// Deps records the modification times of the file and those it
+	// imports, directly or otherwise, when they were verified.
+	Deps map[string]time.Time
+}
+
+// Importer resolves the paths of import statements to Modules.
+type Importer interface {
+	// Import returns the Module at path, imported by the last of chain, a
+	// chain of files importing one another. Proof obligations in the
+	// Module are bounded by timeout.
+	Import(ctx context.Context, path string, chain []string,
+		timeout time.Duration) (*Module, error)
+}
+
+// FileImporter imports modules from the file system. The path of an import
+// names a file without its extension: it is sought relative to the
+// directory of the importing file and each enclosing it, then relative to
+// each directory of SearchPath. Modules are cached until their files change.
+type FileImporter struct {
+	SearchPath []string
+
+	mu    sync.Mutex
+	cache map[string]*Module
+}
+
+func NewFileImporter(searchPath []string) *FileImporter {
+	return &FileImporter{SearchPath: searchPath, cache: map[string]*Module{}}
+}
+
+const extension = ".i2"
+
+// absolute returns the absolute form of file, or file itself if it has none.
+func absolute(file string) string {
+	if abs, err := filepath.Abs(file); err == nil {
+		return abs
+	}
+	return file
+}
+
+// display returns file relative to the working directory if it lies within
+// it, for brevity.
+func display(file string) string {
+	wd, err := os.Getwd()
+	if err != nil {
+		return file
+	}
+	rel, err := filepath.Rel(wd, file)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return file
+	}
+	return rel
+}
+
+// resolve returns the file named by path as imported from the directory dir.
+func (fi *FileImporter) resolve(path, dir string) (string, error) {
+	name := filepath.FromSlash(path)
+	if filepath.Ext(name) != extension {
+		name += extension
+	}
+	if filepath.IsAbs(name) {
+		return name, nil
+	}
+	dirs := []string{}
+	for d := dir; ; d = filepath.Dir(d) {
+		dirs = append(dirs, d)
+		if filepath.Dir(d) == d {
+			break
+		}
+	}
+	dirs = append(dirs, fi.SearchPath...)
+	for _, d := range dirs {
+		file := absolute(filepath.Join(d, name))
+		if info, err := os.Stat(file); err == nil && info.Mode().IsRegular() {
+			return file, nil
+		}
+	}
+	searched := make([]string, len(dirs))
+	for i := range dirs {
+		searched[i] = display(dirs[i])
+	}
+	return "", &diag.Diagnostic{
+		Severity: diag.Error,
+		Code:     diag.Import,
+		Message:  fmt.Sprintf("module `%s' not found", path),
+		Notes:    []string{"searched\n\t" + strings.Join(searched, "\n\t")},
+	}
+}
+
+func (fi *FileImporter) Import(ctx context.Context, path string,
+	chain []string, timeout time.Duration) (*Module, error) {
+	dir := "."
+	if len(chain) > 0 {
+		dir = filepath.Dir(chain[len(chain)-1])
+	}
+	file, err := fi.resolve(path, absolute(dir))
+	if err != nil {
+		return nil, err
+	}
+	for i, f := range chain {
+		if f == file {
+			cycle := make([]string, 0, len(chain)-i+1)
+			for _, f := range append(chain[i:], file) {
+				cycle = append(cycle, display(f))
+			}
+			return nil, diag.Errorf(diag.Import, diag.Span{},
+				"import cycle: %s", strings.Join(cycle, " imports "))
+		}
+	}
+	if m := fi.cached(file); m != nil {
+		return m, nil
+	}
+	m, err := fi.load(ctx, file, chain, timeout)
+	if err != nil {
+		return nil, err
+	}
+	fi.mu.Lock()
+	fi.cache[file] = m
+	fi.mu.Unlock()
+	return m, nil
+}
+
+// cached returns the cached Module for file if none of its dependencies has
+// since changed.
+func (fi *FileImporter) cached(file string) *Module {
+	fi.mu.Lock()
+	m, ok := fi.cache[file]
+	fi.mu.Unlock()
+	if !ok {
+		return nil
+	}
+	for dep, modTime := range m.Deps {
+		info, err := os.Stat(dep)
+		if err != nil || !info.ModTime().Equal(modTime) {
+			return nil
+		}
+	}
+	return m
+}
+
+func (fi *FileImporter) load(ctx context.Context, file string,
+	chain []string, timeout time.Duration) (*Module, error) {
+	info, err := os.Stat(file)
+	if err != nil {
+		return nil, err
+	}
+	src, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	l := newLexer(string(src))
+	l.verifier = &verifier{ctx, timeout}
+	l.importer = fi
+	l.chain = append(chain[:len(chain):len(chain)], file)
+	l.deps[file] = info.ModTime()
+	yyParse(l)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	name := l.module
+	if name == "" {
+		name = strings.TrimSuffix(filepath.Base(file), extension)
+	}
+	m := &Module{
+		Name:    name,
+		File:    file,
+		Exports: symbol.Table{},
+		Result:  l.result(),
+		Symbols: symbol.Table{},
+		Deps:    l.deps,
+	}
+	names := m.qualifiedNames(l)
+	for name, sym := range l.sigma {
+		if _, ok := names[name]; !ok {
+			continue
+		}
+		if _, ok := l.imported[name]; ok || strings.Contains(name, ".") {
+			m.Symbols[names[name]] = sym
+		} else {
+			m.Symbols[names[name]] = symbol.Qualify(sym, name, names)
+		}
+	}
+	for name, sym := range l.exports {
+		m.Exports[name] = symbol.Qualify(sym, name, names)
+	}
+	return m, nil
+}
+
+// qualifiedNames maps the names of the symbols bound by l, but for the
+// builtins, to their qualified names: those declared by the file are
+// qualified by the name of m, and those imported already are.
+func (m *Module) qualifiedNames(l *lexer) map[string]string {
+	names := map[string]string{}
+	for name, sym := range l.sigma {
+		_, imported := l.imported[name]
+		switch {
+		case imported || strings.Contains(name, "."):
+			names[name] = qualifiedName(sym, name)
+		case sym == builtins[name]:
+		default:
+			names[name] = m.Name + "." + name
+		}
+	}
+	return names
+}
+
+// qualifiedName returns the qualified name of the imported symbol sym bound
+// as name.
+func qualifiedName(sym symbol.Scope, name string) string {
+	switch sym := sym.(type) {
+	case symbol.Function:
+		return sym.Name
+	case symbol.Template:
+		return sym.Name
+	case symbol.QualifiedTerm:
+		return sym.Name
+	}
+	return name
+}
+
+// declare binds name to sym, for export if export is set.
+func (l *lexer) declare(name string, sym symbol.Scope, export bool) {
+	l.sigma[name] = sym
+	l.declared = true
+	delete(l.imported, name)
+	if export {
+		l.exports[name] = sym
+	}
+}
+
+func (l *lexer) declareModule(name string, s diag.Span) {
+	if l.module != "" {
+		l.errorf(diag.Import, s, "module already declared as `%s'", l.module)
+		return
+	}
+	l.module = name
+}
+
+// importModule binds the symbols exported by the module at path, qualified
+// by the name of the module and, unless that would shadow another symbol,
+// unqualified. The symbols their definitions refer to are bound by their
+// qualified names.
+func (l *lexer) importModule(path string, s diag.Span) {
+	if l.importer == nil {
+		l.errorf(diag.Import, s, "cannot import `%s' here", path)
+		return
+	}
+	m, err := l.importer.Import(l.ctx, path, l.chain, l.timeout)
+	if err != nil {
+		l.report(diag.From(err, diag.Import, s))
+		return
+	}
+	if errs := moduleErrors(m); len(errs) > 0 {
+		l.report(diag.Diagnostic{
+			Severity: diag.Error,
+			Code:     diag.Import,
+			Span:     s,
+			Message: fmt.Sprintf(
+				"module `%s' has %d errors", path, len(errs),
+			),
+			Notes: errs,
+		})
+	}
+	for dep, modTime := range m.Deps {
+		l.deps[dep] = modTime
+	}
+	l.declared = true
+	for name, sym := range m.Symbols {
+		l.sigma[name] = sym
+	}
+	names := make([]string, 0, len(m.Exports))
+	for name := range m.Exports {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		sym := m.Exports[name]
+		if from, ok := l.imported[name]; ok && from == m.File {
+			continue
+		}
+		qualified := m.Name + "." + name
+		l.bindImport(qualified, sym, m)
+		if _, ok := l.sigma[name]; ok {
+			if _, ok := builtins[name]; !ok {
+				l.shadowed(name, qualified, path, s)
+				continue
+			}
+		}
+		l.bindImport(name, sym, m)
+		l.imported[name] = m.File
+	}
+}
+
+// bindImport binds name to sym, exported by m, recording its declaration.
+func (l *lexer) bindImport(name string, sym symbol.Scope, m *Module) {
+	l.sigma[name] = sym
+	imp := Import{Name: name, Module: m}
+	qualified := qualifiedName(sym, name)
+	for _, f := range m.Result.Functions {
+		if m.Name+"."+f.Name == qualified {
+			imp.Symbol, imp.Span = f, f.Span
+		}
+	}
+	for _, t := range m.Result.Templates {
+		if m.Name+"."+t.Template.Name == qualified {
+			imp.Symbol, imp.Span = t.Template, t.Template.Span
+		}
+	}
+	for _, t := range m.Result.Terms {
+		if m.Name+"."+t.Name == qualified {
+			imp.Symbol, imp.Span = t.Type, t.Span
+		}
+	}
+	l.imports = append(l.imports, imp)
+}
+
+// shadowed warns that the symbol name imported from path is not bound
+// unqualified, as another symbol is already.
+func (l *lexer) shadowed(name, qualified, path string, s diag.Span) {
+	l.report(diag.Diagnostic{
+		Severity: diag.Warning,
+		Code:     diag.Import,
+		Span:     s,
+		Message: fmt.Sprintf(
+			"`%s' from `%s' is already declared", name, path,
+		),
+		Notes: []string{fmt.Sprintf("refer to it as `%s'", qualified)},
+	})
+}
+
+// moduleErrors describes the errors found in m.
+func moduleErrors(m *Module) []string {
+	errs := []string{}
+	for _, d := range m.Result.Diagnostics {
+		if d.Severity == diag.Error {
+			errs = append(errs, fmt.Sprintf(
+				"%s:%s: %s", display(m.File), d.Span, d.Message,
+			))
+		}
+	}
+	return errs
+}
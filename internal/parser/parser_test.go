@@ -1,8 +1,16 @@
 package parser
 
 import (
+	"context"
+	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+
+	"git.sr.ht/~lbnz/i2/internal/diag"
+	"git.sr.ht/~lbnz/i2/internal/symbol"
+	"git.sr.ht/~lbnz/i2/internal/truth"
 )
 
 const additionFile = "../../examples/landau/addition-induction.i2"
@@ -12,7 +20,935 @@ func TestParsing(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	if ret := yyParse(&lexer{[]rune(string(input)), 0}); ret != 0 {
+	l := newLexer(string(input))
+	l.importer = NewFileImporter(nil)
+	l.chain = []string{absolute(additionFile)}
+	if ret := yyParse(l); ret != 0 {
 		t.Fatal("returned", ret)
 	}
+	if len(l.diags) > 0 {
+		t.Fatal(l.diags[0].Message)
+	}
+}
+
+func TestCounterexample(t *testing.T) {
+	tbl := symbol.Table{
+		"p": symbol.Type(symbol.Bool),
+		"q": symbol.Type(symbol.Bool),
+	}
+	// p ==> q
+	chain := symbol.RelationChain{symbol.JustifiableBinaryOpExpr{
+		BinaryOpExpr: symbol.BinaryOpExpr{
+			Op: symbol.Impl,
+			E1: symbol.SimpleExpr{Name: "p"},
+			E2: symbol.SimpleExpr{Name: "q"},
+		},
+	}}
+	v := &verifier{context.Background(), truth.DefaultTimeout}
+	steps := v.sound(chain, tbl)
+	if steps[0].Outcome != Refuted {
+		t.Fatalf("expected refuted step, got %s", steps[0].Outcome)
+	}
+	diags := diagnostics(steps)
+	if len(diags) != 1 || diags[0].Code != diag.Unsound {
+		t.Fatalf("expected unsound step, got %v", diags)
+	}
+	if want := "when\n\tp := true\n\tq := false"; diags[0].Notes[0] != want {
+		t.Fatalf("expected %q, got %q", want, diags[0].Notes[0])
+	}
+}
+
+func TestUndecided(t *testing.T) {
+	// valid, but only by an instance beyond those generated
+	input := `@func p(x any) bool;
+@func f(x any) any;
+term a any;
+tmpl t() { true } {
+	p(a) && (x any) { p(x) ==> p(f(x)) }
+==>	[y any] { p(f(f(f(f(f(f(y))))))) };
+};`
+	res, err := Verify(context.Background(), input, Config{Timeout: truth.DefaultTimeout})
+	if err != nil {
+		t.Fatal(err)
+	}
+	step := res.Templates[0].Proofs[0].Steps[0]
+	if step.Outcome != Undecided {
+		t.Fatalf("expected undecided step, got %s", step.Outcome)
+	}
+	d := step.Diagnostic
+	if d.Code != diag.Undecided || d.Severity != diag.Warning {
+		t.Fatalf("expected undecided warning, got %s %s", d.Severity, d.Code)
+	}
+	if len(d.Notes) != 0 {
+		t.Fatalf("expected no counterexample, got %v", d.Notes)
+	}
+}
+
+func TestDiagnostics(t *testing.T) {
+	input := `@func p(x any) bool;
+tmpl a() { p(1) } {
+	p(1)
+==>	q(1);
+};
+tmpl b() { p(1) } {
+	true
+==>	p(1);
+};`
+	res, err := Verify(context.Background(), input, Config{Timeout: truth.DefaultTimeout})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Templates) != 2 {
+		t.Fatalf("expected 2 templates, got %d", len(res.Templates))
+	}
+	if qed := res.Templates[0].Proofs[0].QED; qed.Outcome != Skipped {
+		t.Fatalf("expected skipped qed, got %s", qed.Outcome)
+	}
+	diags := res.Diagnostics
+	if len(diags) != 2 {
+		t.Fatalf("expected 2 diagnostics, got %v", diags)
+	}
+	if d := diags[0]; d.Code != diag.Analysis || d.Span.Start.Line != 4 ||
+		d.Span.Start.Column != 5 {
+		t.Fatalf("unexpected diagnostic %+v", d)
+	}
+	if d := diags[1]; d.Code != diag.Unsound || d.Span.Start.Line != 7 {
+		t.Fatalf("unexpected diagnostic %+v", d)
+	}
+}
+
+func TestRefutedLemma(t *testing.T) {
+	input := `@func p(x any) bool;
+@func q(x any) bool;
+term c any;
+tmpl t() { p(c) ==> p(c) } {
+lem:
+	p(c)
+==>	q(c);
+	p(c)
+==>	p(c);
+};`
+	res, err := Verify(context.Background(), input, Config{Timeout: truth.DefaultTimeout})
+	if err != nil {
+		t.Fatal(err)
+	}
+	prf := res.Templates[0].Proofs[0]
+	if step := prf.Preamble[0].Steps[0]; step.Outcome != Refuted {
+		t.Fatalf("expected refuted lemma, got %s", step.Outcome)
+	}
+	if step := prf.Steps[0]; step.Outcome != Proven {
+		t.Fatalf("expected proven step, got %s", step.Outcome)
+	}
+	if prf.QED.Outcome != Skipped {
+		t.Fatalf("expected skipped qed, got %s", prf.QED.Outcome)
+	}
+}
+
+func TestImport(t *testing.T) {
+	dir := t.TempDir()
+	for name, src := range map[string]string{
+		"logic/p.i2": `mod logic;
+export @func p(x any) bool;
+export @tmpl ax(x any) { p(x) };
+@func hidden(x any) bool;
+export func q(x any) { hidden(x) };`,
+		"clash.i2": `@func p(x any) bool;
+@func hidden(x any) bool;
+import "logic/p";
+tmpl same() { p(1) === logic.p(1) } {
+	p(1)
+===	logic.p(1);
+};
+tmpl cited() { p(1) } {
+	true
+==> { logic.ax(1) }
+	p(1);
+};
+tmpl unfolded() { q(1) === hidden(1) } {
+	q(1)
+===	hidden(1);
+};`,
+		"main.i2": `@func p(x any) bool;
+import "logic/p";
+tmpl thm() { logic.p(1) } {
+	true
+==> { logic.ax(1) }
+	logic.p(1);
+};`,
+		"a.i2":      `import "b";`,
+		"b.i2":      `import "a";`,
+		"absent.i2": `import "nowhere";`,
+	} {
+		file := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	fi := NewFileImporter(nil)
+	verify := func(name string) *Result {
+		file := filepath.Join(dir, name)
+		src, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := Verify(context.Background(), string(src), Config{
+			Timeout: truth.DefaultTimeout, File: file, Importer: fi,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+
+	res := verify("main.i2")
+	if len(res.Diagnostics) != 1 || res.Diagnostics[0].Severity != diag.Warning {
+		t.Fatalf("expected a warning of shadowing, got %v", res.Diagnostics)
+	}
+	if qed := res.Templates[0].Proofs[0].QED; qed.Outcome != Proven {
+		t.Fatalf("expected proven qed, got %s", qed.Outcome)
+	}
+	m, err := fi.Import(context.Background(), "logic/p",
+		[]string{filepath.Join(dir, "main.i2")}, truth.DefaultTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Name != "logic" || len(m.Exports) != 3 {
+		t.Fatalf("unexpected module %s exporting %v", m.Name, m.Exports)
+	}
+	if fi.cached(m.File) != m {
+		t.Fatal("module not cached")
+	}
+
+	for _, tmpl := range verify("clash.i2").Templates {
+		if step := tmpl.Proofs[0].Steps[0]; step.Outcome == Proven {
+			t.Fatalf("%s: expected imported symbols to be named apart",
+				tmpl.Template.Name)
+		}
+	}
+
+	for name, want := range map[string]string{
+		"a.i2":      "import cycle: ",
+		"absent.i2": "module `nowhere' not found",
+	} {
+		diags := verify(name).Diagnostics
+		if len(diags) == 0 || !strings.Contains(fmt.Sprint(diags[0]), want) {
+			t.Fatalf("%s: expected %q, got %v", name, want, diags)
+		}
+	}
+}
+
+func TestQuantifiers(t *testing.T) {
+	input := `@func r(x any, y any) bool;
+tmpl swap() { [y any] { (x any) { r(x, y) } } ==> (x any) { [y any] { r(x, y) } } } {
+	[y any] { (x any) { r(x, y) } }
+==>	(x any) { [y any] { r(x, y) } };
+};
+tmpl converse() { (x any) { [y any] { r(x, y) } } ==> [y any] { (x any) { r(x, y) } } } {
+	(x any) { [y any] { r(x, y) } }
+==>	[y any] { (x any) { r(x, y) } };
+};`
+	res, err := Verify(context.Background(), input, Config{Timeout: truth.DefaultTimeout})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Templates) != 2 {
+		t.Fatalf("expected 2 templates, got %d", len(res.Templates))
+	}
+	// the converse is invalid, but no finite set of instances shows it
+	for i, want := range []Outcome{Proven, Undecided} {
+		step := res.Templates[i].Proofs[0].Steps[0]
+		if step.Outcome != want {
+			t.Fatalf("%s: expected %s, got %s", step.Expr, want, step.Outcome)
+		}
+	}
+	if p := res.Templates[0].Proofs[0].Steps[0].P.String(); !strings.Contains(p, "(∃y)(∀x)r(x, y)") {
+		t.Fatalf("unexpected proposition %s", p)
+	}
+}
+
+func TestQuantifierTypes(t *testing.T) {
+	input := `@func p(x any) bool;
+@tmpl ax() { (n nat) { p(n) } };
+tmpl bad(y any) { p(y) } {
+	true
+==> { ax() }
+	p(y);
+};
+tmpl good(y nat) { p(y) } {
+	true
+==> { ax() }
+	p(y);
+};
+tmpl witness(y any) { [x nat] { p(x) } ==> p(y) } {
+	[x nat] { p(x) }
+==>	p(y);
+};`
+	res, err := Verify(context.Background(), input, Config{Timeout: truth.DefaultTimeout})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []Outcome{Refuted, Proven, Refuted} {
+		step := res.Templates[i+1].Proofs[0].Steps[0]
+		if step.Outcome != want {
+			t.Fatalf("%s: expected %s, got %s", step.Expr, want, step.Outcome)
+		}
+	}
+}
+
+func TestCapture(t *testing.T) {
+	input := `@func in(x any, A any) bool;
+@tmpl ax(A any) { (x any) { in(x, A) } };
+func subset(A any, B any) { (x any) { in(x, A) ==> in(x, B) } };
+term c any;
+tmpl cited(x any) { (y any) { in(y, y) } } {
+	true
+==> { ax(x) }
+	(y any) { in(y, y) };
+};
+tmpl unfolded(x any) { subset(x, c) ==> (y any) { in(y, y) ==> in(y, c) } } {
+	subset(x, c)
+==>	(y any) { in(y, y) ==> in(y, c) };
+};`
+	res, err := Verify(context.Background(), input, Config{Timeout: truth.DefaultTimeout})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tmpl := range res.Templates[1:] {
+		if step := tmpl.Proofs[0].Steps[0]; step.Outcome == Proven {
+			t.Fatalf("%s: expected capture to be avoided", step.Expr)
+		}
+	}
+}
+
+func TestDefinitions(t *testing.T) {
+	input := `@func in(x any, A any) bool;
+func subset(A any, B any) { (x any) { in(x, A) ==> in(x, B) } };
+func loop(A any) { loop(A) };
+term a any;
+tmpl refl() { subset(a, a) } {
+	true
+===	(x any) { in(x, a) ==> in(x, a) }
+===	subset(a, a);
+};`
+	res, err := Verify(context.Background(), input, Config{Timeout: truth.DefaultTimeout})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Functions) != 3 || res.Functions[1].Body == nil ||
+		res.Functions[2].Body != nil {
+		t.Fatalf("unexpected functions %v", res.Functions)
+	}
+	if len(res.Diagnostics) != 1 || res.Diagnostics[0].Span.Start.Line != 3 {
+		t.Fatalf("expected recursion to be reported, got %v", res.Diagnostics)
+	}
+	if qed := res.Templates[0].Proofs[0].QED; qed.Outcome != Proven {
+		t.Fatalf("expected proven qed, got %s", qed.Outcome)
+	}
+}
+
+func TestTypeAssertions(t *testing.T) {
+	input := `@func nat(x any) bool;
+term 1 nat;
+@func succ(x nat) nat;
+@tmpl one() { 1 nat };
+@tmpl closed(x nat) { x nat ==> succ(x) nat };
+tmpl two() { succ(1) nat } {
+	true
+==> { one() }
+	1 nat
+==> { closed(1) }
+	succ(1) nat;
+};
+tmpl some() { [A any] A nat } {
+	true
+==> { one() }
+	1 nat
+==>	[A any] A nat;
+};
+tmpl bad() { 1 bool } { true ==> 1 bool; };`
+	res, err := Verify(context.Background(), input, Config{Timeout: truth.DefaultTimeout})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tmpl := range res.Templates[2:4] {
+		if qed := tmpl.Proofs[0].QED; qed.Outcome != Proven {
+			t.Fatalf("%s: expected proven qed, got %v", tmpl.Template.Name, qed)
+		}
+	}
+	if p := res.Templates[2].Proofs[0].Steps[1].P.String(); !strings.Contains(p, "nat(succ(1))") {
+		t.Fatalf("unexpected proposition %s", p)
+	}
+	if len(res.Diagnostics) != 1 || res.Diagnostics[0].Span.Start.Line != 19 {
+		t.Fatalf("expected `bool' to be rejected, got %v", res.Diagnostics)
+	}
+}
+
+func TestInvocationErrors(t *testing.T) {
+	input := `@func nat(x any) bool;
+term a any;
+term 1 nat;
+@func p(x nat) bool;
+@tmpl pred(P func(any) bool) { P(a) };
+tmpl t() { p(1) } {
+	true
+==> { pred(p) }
+	p(1);
+};`
+	res, err := Verify(context.Background(), input, Config{Timeout: truth.DefaultTimeout})
+	if err != nil {
+		t.Fatal(err)
+	}
+	diags := res.Diagnostics
+	if len(diags) != 1 {
+		t.Fatalf("expected 1 diagnostic, got %v", diags)
+	}
+	if d := diags[0]; d.Span.Start.Line != 8 || d.Span.Start.Column != 12 ||
+		!strings.Contains(d.Message, "requires type `func(any) bool'") {
+		t.Fatalf("unexpected diagnostic %+v", d)
+	}
+}
+
+func TestTypes(t *testing.T) {
+	for _, typ := range []string{
+		"nat", "func(nat) bool", "func(func(nat) bool, any) nat", "func() bool",
+	} {
+		input := fmt.Sprintf("@tmpl t(P %s) { true };", typ)
+		res, err := Verify(context.Background(), input, Config{Timeout: truth.DefaultTimeout})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := res.Templates[0].Template.Params[0].Type
+		if _, isFunc := got.(symbol.FuncType); got.String() != typ ||
+			isFunc != strings.HasPrefix(typ, "func") {
+			t.Errorf("parsed %s as %#v", typ, got)
+		}
+	}
+	for _, typ := range []string{
+		"func", "func(nat bool", "func(nat)", "nat)", "func(,) bool",
+	} {
+		input := fmt.Sprintf("@tmpl t(P %s) { true };", typ)
+		res, err := Verify(context.Background(), input, Config{Timeout: truth.DefaultTimeout})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(res.Diagnostics) == 0 || res.Diagnostics[0].Code != diag.Syntax {
+			t.Errorf("%s: expected syntax error, got %v", typ, res.Diagnostics)
+		}
+	}
+}
+
+func TestEquality(t *testing.T) {
+	input := `@func succ(x any) any;
+@func p(x any) bool;
+term a any;
+term b any;
+tmpl congruent() { a == b ==> succ(a) == succ(b) } {
+	a == b ==> succ(a) == succ(b);
+};
+tmpl substitution() { a == b && p(a) ==> p(b) } {
+	a == b && p(a) ==> p(b);
+};
+tmpl injective() { succ(a) == succ(b) ==> a == b } {
+	succ(a) == succ(b) ==> a == b;
+};`
+	res, err := Verify(context.Background(), input, Config{Timeout: truth.DefaultTimeout})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []Outcome{Proven, Proven, Refuted} {
+		step := res.Templates[i].Proofs[0].Steps[0]
+		if step.Outcome != want {
+			t.Fatalf("%s: expected %s, got %s", step.Expr, want, step.Outcome)
+		}
+	}
+}
+
+func TestInduction(t *testing.T) {
+	input := `@func nat(x any) bool;
+term 1 nat;
+@func succ(x nat) nat;
+@func even(x nat) bool;
+@tmpl induction(P func(nat) bool) {
+	P(1) && (x nat) { P(x) ==> P(succ(x)) } ==> (x nat) { P(x) }
+};
+@tmpl one() { !even(1) };
+@tmpl odd(x nat) { !even(x) ==> !even(succ(x)) };
+@tmpl alternate(x nat) { !even(x) ==> even(succ(x)) };
+tmpl never(x nat) { !even(x) } induction on x using induction {
+base:	this(1) <== { one() } true;
+step:	this(x) ==> { odd(x) } this(succ(x));
+};
+tmpl wrong(x nat) { !even(x) } induction on x using induction {
+base:	this(1) <== { one() } true;
+step:	this(x) ==> { alternate(x) } even(succ(x));
+};
+tmpl missing(x nat) { !even(x) } induction on x using induction {
+base:	this(1) <== { one() } true;
+};
+tmpl axiom(x nat) { !even(x) } induction on x using one {
+base:	this(1) <== { one() } true;
+step:	this(x) ==> { odd(x) } this(succ(x));
+};`
+	res, err := Verify(context.Background(), input, Config{Timeout: truth.DefaultTimeout})
+	if err != nil {
+		t.Fatal(err)
+	}
+	never := res.Templates[4].Proofs[0]
+	if never.QED.Outcome != Proven || len(never.Subproofs) != 2 {
+		t.Fatalf("expected proven induction, got %+v", never)
+	}
+	wrong := res.Templates[5].Proofs[0]
+	if wrong.Subproofs[1].QED.Outcome != Refuted || wrong.QED.Outcome != Skipped {
+		t.Fatalf("expected refuted step, got %+v", wrong)
+	}
+	var got []string
+	for _, d := range res.Diagnostics {
+		got = append(got, fmt.Sprintf("%d: %s", d.Span.Start.Line, d.Message))
+	}
+	for i, want := range []string{
+		"17: qed burden",
+		"19: missing case `step'",
+		"22: `one' is not an axiom of induction",
+	} {
+		if i >= len(got) || !strings.HasPrefix(got[i], want) {
+			t.Fatalf("expected %q, got %q", want, got)
+		}
+	}
+}
+
+func TestInductionInstance(t *testing.T) {
+	input := `@func succ(x nat) nat;
+@func even(x nat) bool;
+@tmpl induction(P func(nat) bool) {
+	P(1) && (x nat) { P(x) ==> P(succ(x)) } ==> (x nat) { P(x) }
+};
+@tmpl odd(x nat) { !even(x) ==> !even(succ(x)) };
+tmpl never(x nat) { !even(x) } induction on x using induction {
+base:	this(1) <== true;
+step:	this(x) ==> { odd(x) } this(succ(x));
+};`
+	res, err := Verify(context.Background(), input, Config{Timeout: truth.DefaultTimeout})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Diagnostics) != 1 {
+		t.Fatalf("expected 1 diagnostic, got %v", res.Diagnostics)
+	}
+	d := res.Diagnostics[0]
+	if d.Code != diag.Proof || d.Span.Start.Line != 7 ||
+		d.Span.Start.Column != 47 ||
+		strings.Count(d.Message, "argument `1'") != 1 ||
+		strings.Contains(d.Message, "error:") {
+		t.Fatalf("unexpected diagnostic %+v", d)
+	}
+	if qed := res.Templates[2].Proofs[0].QED; qed.Outcome != Invalid {
+		t.Fatalf("expected invalid qed, got %s", qed.Outcome)
+	}
+}
+
+func TestCases(t *testing.T) {
+	input := `@func p(x any) bool;
+@func q(x any) bool;
+term a any;
+term b any;
+@tmpl if_p(x any) { p(x) ==> q(x) };
+@tmpl unless_p(x any) { !p(x) ==> q(x) };
+tmpl either() { q(a) } cases {
+case p(a): p(a) ==> { if_p(a) } q(a);
+case !p(a): !p(a) ==> { unless_p(a) } q(a);
+};
+tmpl partial() { q(a) } cases {
+case p(a): p(a) ==> { if_p(a) } q(a);
+case !p(a) && p(b): !p(a) && p(b) ==> { unless_p(a) } q(a);
+};
+tmpl exhaustive() { q(a) } cases {
+case p(a): p(a) ==> { if_p(a) } q(a);
+case p(b) || !p(a): !p(a) ==> { unless_p(a) } q(a);
+};`
+	res, err := Verify(context.Background(), input, Config{Timeout: truth.DefaultTimeout})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if qed := res.Templates[2].Proofs[0].QED; qed.Outcome != Proven {
+		t.Fatalf("expected proven cases, got %+v", qed)
+	}
+	partial := res.Templates[3].Proofs[0]
+	if partial.QED.Outcome != Refuted ||
+		!strings.Contains(partial.QED.Diagnostic.Message, "disjunction of the cases") {
+		t.Fatalf("expected cases not to be exhaustive, got %+v", partial.QED)
+	}
+	exhaustive := res.Templates[4].Proofs[0]
+	if exhaustive.Subproofs[1].QED.Outcome != Refuted || exhaustive.QED.Outcome != Skipped {
+		t.Fatalf("expected refuted case, got %+v", exhaustive)
+	}
+}
+
+func TestCasesContext(t *testing.T) {
+	input := `@func nat(x any) bool;
+@func p(x any) bool;
+@func q(x any) bool;
+@func r(x any) bool;
+term a any;
+@tmpl if_p(x any) { p(x) ==> q(x) };
+@tmpl if_r(x any) { r(x) ==> q(x) };
+@tmpl unless_p(x any) { !p(x) ==> q(x) };
+tmpl assumed() { p(a) || r(a) ==> q(a) } cases {
+case p(a): p(a) ==> { if_p(a) } q(a);
+case r(a): r(a) ==> { if_r(a) } q(a);
+};
+tmpl typed(n nat) { q(n) } cases {
+case p(n) && nat(n): p(n) && nat(n) ==> { if_p(n) } q(n);
+case !p(n): !p(n) ==> { unless_p(n) } q(n);
+};
+tmpl untyped(n any) { q(n) } cases {
+case p(n) && nat(n): p(n) && nat(n) ==> { if_p(n) } q(n);
+case !p(n): !p(n) ==> { unless_p(n) } q(n);
+};`
+	res, err := Verify(context.Background(), input, Config{Timeout: truth.DefaultTimeout})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []Outcome{Proven, Proven, Refuted} {
+		tmpl := res.Templates[i+3]
+		if qed := tmpl.Proofs[0].QED; qed.Outcome != want {
+			t.Fatalf("%s: expected %s cases, got %+v",
+				tmpl.Template.Name, want, qed)
+		}
+	}
+}
+
+func TestContradiction(t *testing.T) {
+	input := `@func p(x any) bool;
+@func q(x any) bool;
+term a any;
+@tmpl ax() { p(a) ==> q(a) };
+@tmpl not_q() { !q(a) };
+tmpl not_p() { !p(a) } contradiction {
+	true
+==> { hypothesis() }
+	p(a)
+==> { ax() }
+	q(a)
+==> { not_q() }
+	false;
+};
+tmpl unfinished() { !p(a) } contradiction {
+	true
+==> { hypothesis() }
+	p(a);
+};
+tmpl uncited() { !p(a) } {
+	true
+==> { hypothesis() }
+	p(a);
+};`
+	res, err := Verify(context.Background(), input, Config{Timeout: truth.DefaultTimeout})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if qed := res.Templates[2].Proofs[0].QED; qed.Outcome != Proven {
+		t.Fatalf("expected proven contradiction, got %+v", qed)
+	}
+	diags := res.Diagnostics
+	if len(diags) != 2 {
+		t.Fatalf("expected 2 diagnostics, got %v", diags)
+	}
+	if d := diags[0]; d.Span.Start.Line != 18 ||
+		d.Message != "proof by contradiction ends in `p(a)' rather than `false'" {
+		t.Fatalf("unexpected diagnostic %+v", d)
+	}
+	if d := diags[1]; d.Span.Start.Line != 22 ||
+		!strings.Contains(d.Message, "`hypothesis' not defined") {
+		t.Fatalf("unexpected diagnostic %+v", d)
+	}
+}
+
+func TestAssumption(t *testing.T) {
+	input := `@func p(x any) bool;
+@func q(x any) bool;
+term a any;
+@tmpl ax() { p(a) ==> q(a) };
+tmpl direct() { p(a) ==> q(a) } assume p(a) {
+	true
+==> { hypothesis() }
+	p(a)
+==> { ax() }
+	q(a);
+};
+tmpl labelled() { p(a) && q(a) ==> q(a) } assume both: p(a) && q(a) {
+	true
+==> { both() }
+	q(a);
+};
+tmpl stronger() { p(a) ==> q(a) } assume p(a) && q(a) {
+	true
+==> { hypothesis() }
+	q(a);
+};
+tmpl simple() { q(a) } assume p(a) {
+	true ==> q(a);
+};`
+	res, err := Verify(context.Background(), input, Config{Timeout: truth.DefaultTimeout})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tmpl := range res.Templates[1:3] {
+		if qed := tmpl.Proofs[0].QED; qed.Outcome != Proven {
+			t.Fatalf("%s: expected proven qed, got %+v", tmpl.Template.Name, qed)
+		}
+	}
+	if qed := res.Templates[3].Proofs[0].QED; qed.Outcome != Refuted ||
+		!strings.Contains(qed.Diagnostic.Message, "`antecedent ==> assumption'") {
+		t.Fatalf("expected assumption not to follow, got %+v", qed)
+	}
+	if qed := res.Templates[4].Proofs[0].QED; qed.Outcome != Invalid ||
+		!strings.Contains(qed.Diagnostic.Message, "is not an implication") {
+		t.Fatalf("expected assertion not to be an implication, got %+v", qed)
+	}
+}
+
+func TestKeywordIdentifiers(t *testing.T) {
+	input := `@func on(x any) bool;
+@func using(x any) bool;
+term case any;
+term assume any;
+@tmpl cases(x any) { on(x) ==> using(x) };
+@tmpl contradiction(x any) { !on(x) ==> using(x) };
+tmpl hyp() { on(case) ==> using(case) } assume induction: on(case) {
+	true ==> { induction() } on(case) ==> { cases(case) } using(case);
+};
+tmpl split() { using(assume) } cases {
+case on(assume): on(assume) ==> { cases(assume) } using(assume);
+case !on(assume): !on(assume) ==> { contradiction(assume) } using(assume);
+};`
+	res, err := Verify(context.Background(), input, Config{Timeout: truth.DefaultTimeout})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Diagnostics) != 0 {
+		t.Fatalf("expected no diagnostics, got %+v", res.Diagnostics)
+	}
+	for _, tmpl := range res.Templates[2:] {
+		if qed := tmpl.Proofs[0].QED; qed.Outcome != Proven {
+			t.Fatalf("%s: expected proven qed, got %+v", tmpl.Template.Name, qed)
+		}
+	}
+}
+
+func TestCitations(t *testing.T) {
+	input := `@func eq(x any, y any) bool;
+@func succ(x any) any;
+term a any;
+term b any;
+@tmpl injectivity(x any, y any) { eq(succ(x), succ(y)) ==> eq(x, y) };
+@tmpl symmetric(x any, y any) { eq(x, y) ==> eq(y, x) };
+tmpl both() { eq(succ(a), succ(b)) ==> eq(b, a) } {
+	eq(succ(a), succ(b))
+==> { injectivity(a, b), symmetric(a, b) }
+	eq(b, a);
+};
+tmpl labelled() { eq(succ(a), succ(b)) ==> eq(b, a) } {
+flipped:	eq(a, b) ==> { symmetric(a, b) } eq(b, a);
+	eq(succ(a), succ(b))
+==> { injectivity(a, b), flipped }
+	eq(b, a);
+};
+tmpl bad() { eq(succ(a), succ(b)) ==> eq(b, a) } {
+	eq(succ(a), succ(b))
+==> { injectivity(a, b), reflexive(a) }
+	eq(b, a);
+};`
+	res, err := Verify(context.Background(), input, Config{Timeout: truth.DefaultTimeout})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tmpl := range res.Templates[2:4] {
+		if qed := tmpl.Proofs[0].QED; qed.Outcome != Proven {
+			t.Fatalf("%s: expected proven qed, got %+v", tmpl.Template.Name, qed)
+		}
+	}
+	diags := res.Diagnostics
+	if len(diags) != 1 {
+		t.Fatalf("expected 1 diagnostic, got %v", diags)
+	}
+	if d := diags[0]; d.Span.Start.Line != 20 || d.Span.Start.Column != 26 ||
+		!strings.HasPrefix(d.Message, "justification 2 `reflexive(a)': ") {
+		t.Fatalf("unexpected diagnostic %+v", d)
+	}
+}
+
+func TestLint(t *testing.T) {
+	input := `@func p(x any) bool;
+@func q(x any) bool;
+term a any;
+@tmpl ax() { p(a) ==> q(a) };
+@tmpl unused() { q(a) };
+tmpl needless() { p(a) ==> p(a) } {
+	p(a) ==> { ax() } p(a);
+};
+tmpl redundant() { p(a) ==> q(a) } {
+	p(a) ==> { ax(), ax() } q(a);
+};
+tmpl unlabelled() { p(a) ==> q(a) } {
+spare:	p(a) ==> { ax() } q(a);
+	p(a) ==> { ax() } q(a);
+};
+tmpl merge() { p(a) === p(a) } {
+	p(a)
+===	!!p(a)
+===	p(a);
+};`
+	res, err := Verify(context.Background(), input, Config{
+		Timeout: truth.DefaultTimeout, Lint: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, d := range res.Diagnostics {
+		got = append(got, fmt.Sprintf("%d:%d: %s: %s",
+			d.Span.Start.Line, d.Span.Start.Column, d.Severity, d.Message))
+	}
+	want := []string{
+		"7:2: warning: step 1 holds without its justification `ax()'",
+		"10:13: warning: step 1 holds without citing `ax()'",
+		"10:19: warning: step 1 holds without citing `ax()'",
+		"13:8: warning: preamble proof `spare' is never used",
+		"17:2: info: steps 1 and 2 may be merged into `p(a) === p(a)'",
+		"5:1: warning: axiom `unused' is never cited",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("expected\n%s\ngot\n%s",
+			strings.Join(want, "\n"), strings.Join(got, "\n"))
+	}
+}
+
+func TestHints(t *testing.T) {
+	input := `@func eq(x any, y any) bool;
+@func succ(x any) any;
+term a any;
+term b any;
+@tmpl injectivity(x any, y any) { eq(succ(x), succ(y)) ==> eq(x, y) };
+@tmpl symmetric(x any, y any) { eq(x, y) ==> eq(y, x) };
+tmpl flip() { eq(succ(a), succ(b)) ==> eq(b, a) } {
+	eq(succ(a), succ(b))
+==> { injectivity(a, b) }
+	eq(a, b)
+==>	eq(b, a);
+};`
+	res, err := Verify(context.Background(), input, Config{
+		Timeout: truth.DefaultTimeout, HintLine: 11,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, h := range res.Hints {
+		got = append(got, h.Citation.String())
+	}
+	want := []string{"symmetric(a, b)"}
+	if strings.Join(got, "; ") != strings.Join(want, "; ") {
+		t.Fatalf("expected hints %q, got %q", want, got)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	input := `@func eq(x any, y any) bool;
+@func succ(x any) any;
+term a any;
+term b any;
+@tmpl injectivity(x any, y any) { eq(succ(x), succ(y)) ==> eq(x, y) };
+@tmpl symmetric(x any, y any) { eq(x, y) ==> eq(y, x) };
+tmpl flip() { eq(succ(a), succ(b)) ==> eq(b, a) } {
+	eq(succ(a), succ(b)) ==> eq(b, a);
+};`
+	for depth, want := range map[int]string{
+		1: "no chain found of at most 1 steps",
+		2: `	eq(succ(a), succ(b))
+==> { injectivity(a, b) }
+	eq(a, b)
+==> { symmetric(a, b) }
+	eq(b, a)
+`,
+	} {
+		res, err := Verify(context.Background(), input, Config{
+			Timeout: truth.DefaultTimeout,
+			Search: &Search{
+				Line: 8, Depth: depth, Timeout: truth.DefaultTimeout,
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(res.Fills) != 1 {
+			t.Fatalf("expected 1 fill, got %v", res.Fills)
+		}
+		got := res.Fills[0].Source()
+		if d := res.Fills[0].Diagnostic; d != nil {
+			got = d.Message
+		}
+		if got != want {
+			t.Fatalf("depth %d: expected\n%s\ngot\n%s", depth, want, got)
+		}
+	}
+}
+
+func TestSMTLIB(t *testing.T) {
+	input := `@func le(x nat, y nat) bool;
+@func succ(x nat) nat;
+@func pos(x any) bool;
+term zero nat;
+term one pos;
+@tmpl above(x nat) { le(x, succ(x)) };
+tmpl bounded(y nat) { le(y, succ(y)) } {
+	true
+==> { above(zero) }
+	le(zero, succ(zero))
+==> { above(y) }
+	le(y, succ(y));
+};`
+	res, err := Verify(context.Background(), input, Config{
+		Timeout: truth.DefaultTimeout, SMTLIB: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if qed := res.Templates[1].Proofs[0].QED; qed.Outcome != Proven {
+		t.Fatalf("expected proven qed, got %s", qed.Outcome)
+	}
+	if len(res.Scripts) != 2 {
+		t.Fatalf("expected 2 scripts, got %v", res.Scripts)
+	}
+	for i, s := range res.Scripts {
+		if s.Template != "bounded" || s.Index != i+1 {
+			t.Fatalf("expected bounded step %d, got %s step %d",
+				i+1, s.Template, s.Index)
+		}
+	}
+	// nat is asserted by an undeclared predicate, pos by that declared
+	want := "; step 2 of bounded: le(zero, succ(zero)) ==> le(y, succ(y)) by above(y)\n" +
+		`(set-logic ALL)
+(declare-sort any 0)
+(define-sort nat () any)
+(define-sort pos () any)
+(declare-fun nat (nat) Bool)
+(declare-fun pos (any) Bool)
+(declare-fun le (nat nat) Bool)
+(declare-fun succ (nat) nat)
+(declare-const zero nat)
+(declare-const y nat)
+(declare-const one pos)
+(assert (not (=> (and (and (pos one) (nat y)) (nat zero)) (=> (and (le y (succ y)) (le zero (succ zero))) (le y (succ y))))))
+(check-sat)
+`
+	if got := res.Scripts[1].Source; got != want {
+		t.Fatalf("expected\n%s\ngot\n%s", want, got)
+	}
+	if !strings.Contains(res.Scripts[0].Source,
+		"(=> (and (le zero (succ zero)) true) (le zero (succ zero)))") {
+		t.Fatalf("justification not instantiated in\n%s", res.Scripts[0].Source)
+	}
 }
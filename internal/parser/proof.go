@@ -0,0 +1,288 @@
+package parser
+
+import (
+	"fmt"
+
+	"git.sr.ht/~lbnz/i2/internal/diag"
+	"git.sr.ht/~lbnz/i2/internal/symbol"
+	"git.sr.ht/~lbnz/i2/internal/truth"
+)
+
+// Subproof is the proof of an obligation into which a proof Form divides
+// the assertion of its template.
+type Subproof struct {
+	Label string
+	Goal  symbol.Expr
+	ProofResult
+}
+
+// subchains divides the expressions of a proof block into the ProofChains
+// ending in each of the labels, which must each end one.
+func subchains(exprs []labelledJust, s diag.Span, labels ...string) (map[string]symbol.ProofChain, error) {
+	chains := map[string]symbol.ProofChain{}
+	start := 0
+	for i, just := range exprs {
+		if just.label == "" {
+			continue
+		}
+		if _, ok := chains[just.label]; ok {
+			return nil, diag.Errorf(diag.Proof, just.expr.Extent(),
+				"duplicate case `%s'", just.label)
+		}
+		if !contains(labels, just.label) {
+			continue
+		}
+		group := exprs[start : i+1]
+		prf, err := proofChain(group, diag.Join(
+			group[0].expr.Extent(), just.expr.Extent(),
+		))
+		if err != nil {
+			return nil, err
+		}
+		chains[just.label] = *prf
+		start = i + 1
+	}
+	if start != len(exprs) {
+		return nil, diag.Errorf(diag.Proof, exprs[start].expr.Extent(),
+			"proof `%s' does not belong to a case", exprs[start].expr)
+	}
+	for _, lbl := range labels {
+		if _, ok := chains[lbl]; !ok {
+			return nil, diag.Errorf(diag.Proof, s, "missing case `%s'", lbl)
+		}
+	}
+	return chains, nil
+}
+
+func contains(arr []string, s string) bool {
+	for _, t := range arr {
+		if t == s {
+			return true
+		}
+	}
+	return false
+}
+
+// induction assembles a proof by induction on v using axiom, cited at
+// using, from the expressions of its block, which are divided into the
+// `base' and `step' cases.
+func induction(v, axiom string, exprs []labelledJust,
+	using, s diag.Span) (*symbol.Induction, error) {
+	chains, err := subchains(exprs, s, "base", "step")
+	if err != nil {
+		return nil, err
+	}
+	return &symbol.Induction{
+		Var: v, Axiom: axiom,
+		Base: chains["base"], Step: chains["step"],
+		Span: s, Using: using,
+	}, nil
+}
+
+// verifyForm checks the proof of tmpl structured by prf.Form in tbl.
+func (l *lexer) verifyForm(tmpl symbol.Template, prf symbol.ProofChain,
+	tbl symbol.Table) ProofResult {
+	switch form := prf.Form.(type) {
+	case symbol.Induction:
+		return l.verifyInduction(tmpl, prf, form, tbl)
+	case symbol.Cases:
+		return l.verifyCases(tmpl, prf, form, tbl)
+	case symbol.Contradiction:
+		return l.verifyContradiction(tmpl, prf, form, tbl)
+	case symbol.Assumption:
+		return l.verifyAssumption(tmpl, prf, form, tbl)
+	default:
+		panic("unknown proof form")
+	}
+}
+
+// verifyInduction checks the base case and inductive step of a proof by
+// induction against the obligations generated from the axiom, and that its
+// conclusion proves the assertion.
+func (l *lexer) verifyInduction(tmpl symbol.Template, prf symbol.ProofChain,
+	ind symbol.Induction, tbl symbol.Table) ProofResult {
+	presult := ProofResult{Chain: prf}
+	obl, err := ind.Obligations(tmpl, l.sigma)
+	if err != nil {
+		presult.QED = failed(diag.From(err, diag.Proof, ind.Span), 0)
+		l.diags = append(l.diags, *presult.QED.Diagnostic)
+		return presult
+	}
+	if l.linter != nil {
+		l.linter.cited[ind.Axiom] = true
+	}
+	thisTbl := symbol.Table{"this": obl.This}.Nest(tbl)
+	for _, sub := range []struct {
+		label string
+		goal  symbol.Expr
+		chain symbol.ProofChain
+	}{
+		{"base", obl.Base, ind.Base},
+		{"step", obl.Step, ind.Step},
+	} {
+		presult.Subproofs = append(presult.Subproofs, Subproof{
+			Label:       sub.label,
+			Goal:        sub.goal,
+			ProofResult: l.verifyChain(sub.goal, sub.chain, thisTbl),
+		})
+	}
+	for _, sub := range presult.Subproofs {
+		if sub.QED.Outcome != Proven {
+			return presult
+		}
+	}
+	ctx := thisTbl.Nest(l.sigma)
+	presult.QED = l.entails(
+		obl.Conclusion, tmpl.E, "proven", "assertion", ctx, ind.Span,
+	)
+	if presult.QED.Diagnostic != nil {
+		l.diags = append(l.diags, *presult.QED.Diagnostic)
+	}
+	return presult
+}
+
+// entails discharges the obligation that proven entails assertion in tbl,
+// reporting a failure at s in which they are called by the given names.
+func (v *verifier) entails(proven, assertion symbol.Expr,
+	provenName, assertionName string, tbl symbol.Table, s diag.Span) Obligation {
+	provenP, err := proven.Analyse(tbl)
+	if err != nil {
+		return failed(diag.From(err, diag.Analysis, proven.Extent()), 0)
+	}
+	assertionP, err := assertion.Analyse(tbl)
+	if err != nil {
+		return failed(diag.From(err, diag.Analysis, assertion.Extent()), 0)
+	}
+	qed := typed(truth.Impl(provenP.P, assertionP.P), tbl)
+	outcome, dur, err := v.decide(qed)
+	if err == nil && outcome {
+		return Obligation{Outcome: Proven, Duration: dur}
+	}
+	atoms := symbol.Atoms(assertion, tbl)
+	for k, v := range symbol.Atoms(proven, tbl) {
+		atoms[k] = v
+	}
+	return failed(obligationDiagnostic(falsify(
+		fmt.Sprintf("qed burden `%s ==> %s'", provenName, assertionName),
+		qed, err, atoms,
+		[]valuation{
+			{provenName, provenP.P},
+			{assertionName, assertionP.P},
+		},
+	), diag.QED, s), dur)
+}
+
+// verifyCases checks that each case of a proof by case analysis proves the
+// assertion given its condition, and that the cases are exhaustive.
+func (l *lexer) verifyCases(tmpl symbol.Template, prf symbol.ProofChain,
+	cases symbol.Cases, tbl symbol.Table) ProofResult {
+	presult := ProofResult{Chain: prf}
+	for _, c := range cases.Cases {
+		goal := c.Goal(tmpl.E)
+		presult.Subproofs = append(presult.Subproofs, Subproof{
+			Label:       fmt.Sprintf("case %s", c.Cond),
+			Goal:        goal,
+			ProofResult: l.verifyChain(goal, c.Proof, tbl),
+		})
+	}
+	for _, sub := range presult.Subproofs {
+		if sub.QED.Outcome != Proven {
+			return presult
+		}
+	}
+	presult.QED = l.exhaustive(cases, tmpl.E, tbl.Nest(l.sigma))
+	if presult.QED.Diagnostic != nil {
+		l.diags = append(l.diags, *presult.QED.Diagnostic)
+	}
+	return presult
+}
+
+// exhaustive discharges the obligation that the conditions of cases are
+// exhaustive in proving assertion, given its antecedent and the types of
+// the variables of tbl.
+func (v *verifier) exhaustive(cases symbol.Cases, assertion symbol.Expr,
+	tbl symbol.Table) Obligation {
+	if len(cases.Cases) == 0 {
+		return failed(diag.From(
+			fmt.Errorf("no cases"), diag.Proof, cases.Span,
+		), 0)
+	}
+	e := cases.Exhaustion(assertion)
+	aExpr, err := e.Analyse(tbl)
+	if err != nil {
+		return failed(diag.From(err, diag.Analysis, cases.Span), 0)
+	}
+	P := typed(aExpr.P, tbl)
+	outcome, dur, err := v.decide(P)
+	if err == nil && outcome {
+		return Obligation{Outcome: Proven, Duration: dur}
+	}
+	return failed(obligationDiagnostic(falsify(
+		fmt.Sprintf("disjunction of the cases `%s'", e),
+		P, err, symbol.Atoms(e, tbl), nil,
+	), diag.QED, cases.Span), dur)
+}
+
+// verifyContradiction checks a proof by contradiction, which may cite the
+// negation of the assertion as its hypothesis and must end in `false'.
+func (l *lexer) verifyContradiction(tmpl symbol.Template, prf symbol.ProofChain,
+	c symbol.Contradiction, tbl symbol.Table) ProofResult {
+	if err := c.Refutes(); err != nil {
+		presult := ProofResult{Chain: prf}
+		presult.QED = failed(diag.From(err, diag.Proof, c.Span), 0)
+		l.diags = append(l.diags, *presult.QED.Diagnostic)
+		return presult
+	}
+	hyp := c.Hypothesis(tmpl)
+	presult := l.verifyChain(
+		tmpl.E, c.Proof, symbol.Table{hyp.Name: hyp}.Nest(tbl),
+	)
+	presult.Chain = prf
+	return presult
+}
+
+// assumption assembles the proof form `assume' of the expressions of its
+// block, reporting the problems found.
+func (l *lexer) assumption(label string, assumed symbol.Expr,
+	exprs []labelledJust, s, block diag.Span) symbol.Form {
+	prf, err := proofChain(exprs, block)
+	if err != nil {
+		l.report(diag.From(err, diag.Proof, block))
+		return nil
+	}
+	return symbol.Assumption{
+		Label: label, Assumed: assumed, Proof: *prf, Span: s,
+	}
+}
+
+// verifyAssumption checks that the proof of an assumption proves the
+// consequent of the assertion from it, and that the antecedent of the
+// assertion entails the assumption.
+func (l *lexer) verifyAssumption(tmpl symbol.Template, prf symbol.ProofChain,
+	a symbol.Assumption, tbl symbol.Table) ProofResult {
+	presult := ProofResult{Chain: prf}
+	antecedent, goal, err := a.Obligations(tmpl)
+	if err != nil {
+		presult.QED = failed(diag.From(err, diag.Proof, a.Span), 0)
+		l.diags = append(l.diags, *presult.QED.Diagnostic)
+		return presult
+	}
+	hyp := a.Hypothesis()
+	sub := l.verifyChain(goal, a.Proof, symbol.Table{hyp.Name: hyp}.Nest(tbl))
+	presult.Subproofs = []Subproof{{
+		Label:       fmt.Sprintf("assume %s", a.Assumed),
+		Goal:        goal,
+		ProofResult: sub,
+	}}
+	if sub.QED.Outcome != Proven {
+		return presult
+	}
+	presult.QED = l.entails(
+		antecedent, a.Assumed, "antecedent", "assumption",
+		tbl.Nest(l.sigma), a.Span,
+	)
+	if presult.QED.Diagnostic != nil {
+		l.diags = append(l.diags, *presult.QED.Diagnostic)
+	}
+	return presult
+}
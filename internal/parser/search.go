@@ -0,0 +1,161 @@
+package parser
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"git.sr.ht/~lbnz/i2/internal/diag"
+	"git.sr.ht/~lbnz/i2/internal/symbol"
+)
+
+// Search configures the search for chains filling the gaps of failing steps.
+type Search struct {
+	// Line is the line of the steps whose gaps are filled.
+	Line int
+
+	// Depth bounds the steps of the chains sought.
+	Depth int
+
+	// Timeout bounds the search for each chain.
+	Timeout time.Duration
+}
+
+// Fill is a chain of justified steps found to prove a failing step.
+type Fill struct {
+	Step symbol.JustifiableBinaryOpExpr
+
+	// Chain is nil if none was found within the limits of the search, in
+	// which case Diagnostic explains why.
+	Chain      symbol.RelationChain
+	Diagnostic *diag.Diagnostic
+}
+
+// Source returns the chain of f as i2 source.
+func (f Fill) Source() string {
+	var b strings.Builder
+	for i, step := range f.Chain {
+		if i == 0 {
+			fmt.Fprintf(&b, "\t%s\n", step.E1)
+		}
+		if step.Just != nil {
+			fmt.Fprintf(&b, "%s { %s }\n", step.Op, step.Just)
+		} else {
+			fmt.Fprintf(&b, "%s\n", step.Op)
+		}
+		fmt.Fprintf(&b, "\t%s\n", step.E2)
+	}
+	return b.String()
+}
+
+// fillSteps seeks chains proving the failing steps on the line searched.
+func (l *lexer) fillSteps(steps []Step, tbl symbol.Table) {
+	for _, step := range steps {
+		s := step.Expr.Extent()
+		if s.Start.Line > l.search.Line || s.End.Line < l.search.Line ||
+			step.Outcome == Proven {
+			continue
+		}
+		fill := Fill{Step: step.Expr}
+		chain, err := l.fill(step.Expr.BinaryOpExpr, tbl)
+		if err != nil {
+			d := diag.From(err, diag.Proof, s)
+			fill.Diagnostic = &d
+		} else {
+			fill.Chain = chain
+		}
+		l.fills = append(l.fills, fill)
+	}
+}
+
+// searcher seeks a chain of steps from one expression to another by
+// iterative deepening over the citations in scope.
+type searcher struct {
+	*verifier
+	tbl symbol.Table
+	op  symbol.Operator
+	end symbol.Expr
+}
+
+// fill returns a chain of steps by e.Op from e.E1 to e.E2, of at most the
+// depth searched, that examineProof accepts as a proof of e.
+func (l *lexer) fill(e symbol.BinaryOpExpr, tbl symbol.Table) (symbol.RelationChain, error) {
+	switch e.Op {
+	case symbol.Eqv, symbol.Impl, symbol.Fllw:
+	default:
+		return nil, fmt.Errorf("`%s' is not a relation", e)
+	}
+	ctx, cancel := context.WithTimeout(l.ctx, l.search.Timeout)
+	defer cancel()
+	s := &searcher{
+		verifier: &verifier{ctx, l.timeout},
+		tbl:      tbl,
+		op:       e.Op,
+		end:      e.E2,
+	}
+	for depth := 1; depth <= l.search.Depth; depth++ {
+		chain := s.from(e.E1, depth, map[string]bool{e.E1.String(): true})
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("no chain found within %s", l.search.Timeout)
+		}
+		if chain == nil {
+			continue
+		}
+		_, qed := s.examineProof(e, chain, nil, tbl)
+		if qed.Outcome == Proven {
+			return chain, nil
+		}
+	}
+	return nil, fmt.Errorf("no chain found of at most %d steps", l.search.Depth)
+}
+
+// from returns a chain of at most depth steps from cur to the end, visiting
+// no expression twice, or nil if there is none.
+func (s *searcher) from(cur symbol.Expr, depth int, visited map[string]bool) symbol.RelationChain {
+	if s.ctx.Err() != nil {
+		return nil
+	}
+	if s.holds(s.relate(cur, s.end), nil, s.tbl) {
+		return symbol.RelationChain{s.step(cur, s.end, nil)}
+	}
+	terms := append(symbol.Terms(cur), symbol.Terms(s.end)...)
+	cs := citations(terms, s.tbl)
+	for _, c := range cs {
+		just := symbol.Citations{c.expr}
+		if s.holds(s.relate(cur, s.end), just, s.tbl) {
+			return symbol.RelationChain{s.step(cur, s.end, just)}
+		}
+	}
+	if depth == 1 {
+		return nil
+	}
+	for _, c := range cs {
+		just := symbol.Citations{c.expr}
+		for _, next := range symbol.Rewrites(c.assertion, s.op) {
+			key := next.String()
+			if visited[key] || !s.holds(s.relate(cur, next), just, s.tbl) {
+				continue
+			}
+			visited[key] = true
+			rest := s.from(next, depth-1, visited)
+			delete(visited, key)
+			if rest != nil {
+				return append(symbol.RelationChain{
+					s.step(cur, next, just),
+				}, rest...)
+			}
+		}
+	}
+	return nil
+}
+
+func (s *searcher) relate(a, b symbol.Expr) symbol.BinaryOpExpr {
+	return symbol.BinaryOpExpr{Op: s.op, E1: a, E2: b}
+}
+
+func (s *searcher) step(a, b symbol.Expr, just symbol.Citations) symbol.JustifiableBinaryOpExpr {
+	return symbol.JustifiableBinaryOpExpr{
+		BinaryOpExpr: s.relate(a, b), Just: just,
+	}
+}
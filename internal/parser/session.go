@@ -0,0 +1,141 @@
+package parser
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"git.sr.ht/~lbnz/i2/internal/diag"
+	"git.sr.ht/~lbnz/i2/internal/symbol"
+	"git.sr.ht/~lbnz/i2/internal/truth"
+)
+
+// Session verifies input incrementally: the symbols declared by each input
+// are in scope for those following it, until undone. Besides statements, an
+// input may be a single expression, which is evaluated.
+type Session struct {
+	// Importer resolves imports. If it is nil, imports are errors.
+	Importer Importer
+
+	scope   scope
+	history []scope
+	v       *verifier
+}
+
+// scope is what one input to a Session leaves to the next.
+type scope struct {
+	sigma    symbol.Table
+	imported map[string]string
+}
+
+func (sc scope) copy() scope {
+	imported := map[string]string{}
+	for k, v := range sc.imported {
+		imported[k] = v
+	}
+	return scope{symbol.Table{}.Nest(sc.sigma), imported}
+}
+
+// NewSession returns a Session bounding each proof obligation by timeout.
+func NewSession(ctx context.Context, timeout time.Duration) *Session {
+	return &Session{
+		scope: scope{symbol.Table{}.Nest(builtins), map[string]string{}},
+		v:     &verifier{ctx, timeout},
+	}
+}
+
+// Evaluation is the result of evaluating an expression. A relation is
+// evaluated step by step, as in a proof; any other expression is evaluated
+// as a whole.
+type Evaluation struct {
+	Expr symbol.Expr
+
+	// P is the Proposition the expression analyses to, or nil if it is
+	// a relation or fails analysis.
+	P truth.Proposition
+
+	Steps []Step
+	Obligation
+}
+
+// Input is the outcome of a single input to a Session.
+type Input struct {
+	Result
+
+	// Evaluation is nil unless the input was an expression.
+	Evaluation *Evaluation
+}
+
+// Exec parses and verifies input in the scope of the preceding inputs.
+// Imports are resolved relative to the working directory.
+func (s *Session) Exec(input string) *Input {
+	return s.exec(input, "")
+}
+
+// Load is like Exec but for the contents of file, relative to which its
+// imports are resolved.
+func (s *Session) Load(file, input string) *Input {
+	return s.exec(input, file)
+}
+
+func (s *Session) exec(input, file string) *Input {
+	prev := s.scope.copy()
+	l := newLexer(input)
+	l.mode = tkRepl
+	l.sigma = s.scope.sigma
+	l.imported = s.scope.imported
+	l.verifier = s.v
+	l.importer = s.Importer
+	if file != "" {
+		l.chain = []string{absolute(file)}
+	}
+	yyParse(l)
+	if l.declared {
+		s.history = append(s.history, prev)
+	}
+	return &Input{Result: *l.result(), Evaluation: l.evaluation}
+}
+
+// Undo reverts the declarations of the last input that made any, reporting
+// whether there was one.
+func (s *Session) Undo() bool {
+	n := len(s.history)
+	if n == 0 {
+		return false
+	}
+	s.scope, s.history = s.history[n-1], s.history[:n-1]
+	return true
+}
+
+// Lookup returns the symbol declared as name.
+func (s *Session) Lookup(name string) (symbol.Scope, bool) {
+	sym, ok := s.scope.sigma[name]
+	return sym, ok
+}
+
+// evaluate evaluates e in the scope of the symbols declared so far.
+func (l *lexer) evaluate(e symbol.Expr) {
+	ev := &Evaluation{Expr: e}
+	l.evaluation = ev
+	if rel, ok := e.(symbol.JustifiableBinaryOpExpr); ok {
+		ev.Steps = l.sound(rel.Quantise(), l.sigma)
+		l.diags = append(l.diags, diagnostics(ev.Steps)...)
+		return
+	}
+	aExpr, err := e.Analyse(l.sigma)
+	if err != nil {
+		ev.Obligation = failed(diag.From(err, diag.Analysis, e.Extent()), 0)
+		l.report(*ev.Diagnostic)
+		return
+	}
+	ev.P = typed(aExpr.P, l.sigma)
+	outcome, dur, err := l.decide(ev.P)
+	if err == nil && outcome {
+		ev.Obligation = Obligation{Outcome: Proven, Duration: dur}
+		return
+	}
+	ev.Obligation = failed(obligationDiagnostic(falsify(
+		fmt.Sprintf("`%s'", e), ev.P, err, symbol.Atoms(e, l.sigma), nil,
+	), diag.Unsound, e.Extent()), dur)
+	l.report(*ev.Diagnostic)
+}
@@ -0,0 +1,113 @@
+package parser
+
+import (
+	"fmt"
+
+	"git.sr.ht/~lbnz/i2/internal/symbol"
+	"git.sr.ht/~lbnz/i2/internal/truth"
+)
+
+// Script is the SMT-LIB 2 script of the obligation that a step holds, which
+// is valid if the script is unsatisfiable.
+type Script struct {
+	Template string
+
+	// Index is that of the step among those of the proofs of Template,
+	// counted from 1.
+	Index  int
+	Step   symbol.JustifiableBinaryOpExpr
+	Source string
+}
+
+// exportSteps translates the steps verified in tbl into Scripts. Those that
+// are Invalid have no Proposition to translate, and are only counted.
+func (l *lexer) exportSteps(steps []Step, tbl symbol.Table) {
+	name := ""
+	if this, ok := tbl["this"].(symbol.Template); ok {
+		name = this.Name
+	}
+	for _, step := range steps {
+		l.steps++
+		if step.P == nil {
+			continue
+		}
+		l.scripts = append(l.scripts, Script{
+			Template: name,
+			Index:    l.steps,
+			Step:     step.Expr,
+			Source: fmt.Sprintf("; step %d of %s: %s\n%s", l.steps, name,
+				step.Expr, truth.SMTLIB(step.P, signature(step.Expr, tbl))),
+		})
+	}
+}
+
+// signature gives the sorts of the symbols of tbl and of those bound in e:
+// each type other than bool is a sort, and `any' is that of which the others
+// are aliases.
+func signature(e symbol.Expr, tbl symbol.Table) truth.Signature {
+	sig := truth.Signature{
+		Top:   string(symbol.Any),
+		Vars:  map[truth.Variable]string{},
+		Funcs: map[string]truth.Rank{},
+	}
+	for name, sym := range tbl {
+		switch sym := sym.(type) {
+		case symbol.QualifiedTerm:
+			if r, ok := funcRank(sym.Type); ok {
+				sig.Funcs[sym.Name] = r
+			} else {
+				sig.Vars[truth.Variable(sym.Name)] = smtSort(sym.Type)
+			}
+		case symbol.Type:
+			if r, ok := funcRank(sym); ok {
+				sig.Funcs[name] = r
+			} else {
+				sig.Vars[truth.Variable(name)] = smtSort(sym)
+			}
+		case symbol.Function:
+			if sym.Name != "" {
+				name = sym.Name
+			}
+			r := truth.Rank{
+				Args:   make([]string, len(sym.Sig.Params)),
+				Result: smtSort(sym.Sig.Return),
+			}
+			for i, p := range sym.Sig.Params {
+				r.Args[i] = smtSort(p.Type)
+			}
+			sig.Funcs[name] = r
+		}
+	}
+	for _, p := range symbol.Bound(e, tbl) {
+		sig.Vars[truth.Variable(p.Name)] = smtSort(p.Type)
+	}
+	return sig
+}
+
+// funcRank returns the Rank of a parameter of the function type t. The
+// function types among its own parameters have no sort, and are given `any'.
+func funcRank(t symbol.Type) (truth.Rank, bool) {
+	f, ok := t.(symbol.FuncType)
+	if !ok {
+		return truth.Rank{}, false
+	}
+	r := truth.Rank{
+		Args:   make([]string, len(f.Params)),
+		Result: smtSort(f.Return),
+	}
+	for i, p := range f.Params {
+		r.Args[i] = smtSort(p)
+	}
+	return r, true
+}
+
+// smtSort returns the sort of the type t. Function types are given `any'.
+func smtSort(t symbol.Type) string {
+	if t == symbol.Bool {
+		return truth.BoolSort
+	}
+	if _, ok := t.(symbol.BaseType); !ok {
+		return string(symbol.Any)
+	}
+	return t.String()
+}
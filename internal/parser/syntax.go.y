@@ -2,14 +2,10 @@
 	package parser
 
 	import (
-		"fmt"
-		"strings"
-
+		"git.sr.ht/~lbnz/i2/internal/diag"
 		"git.sr.ht/~lbnz/i2/internal/symbol"
 	)
 
-	var sigma = symbol.Table{}
-
 	type labelledJust struct {
 		expr symbol.Expr
 		label string
@@ -17,40 +13,47 @@
 %}
 
 %union{
+	span		diag.Span
 	s		string
-	sarr		[]string
 	n		int
 	b		bool
 	proof		[]labelledJust
 
 	sym_op		symbol.Operator
 	sym_tmpl	symbol.Template
+	sym_form	symbol.Form
+	sym_cases	[]symbol.Case
 	sym_func	symbol.Function
 	sym_type	symbol.Type
+	sym_typearr	[]symbol.Type
 	sym_paramarr	[]symbol.Parameter
 
 	sym_expr	symbol.Expr
 	sym_exprarr	[]symbol.Expr
 	sym_pfexpr	symbol.PostfixExpr
-	sym_pfexpr_ptr	*symbol.PostfixExpr
+	sym_just	symbol.Citations
 }
 
-%type <b> axiom
-%type <s> value type
-%type <sarr> value_list
+%type <b> axiom export
+%type <s> identifier value
+%type <sym_type> type
+%type <sym_typearr> type_list
 
 %type <sym_op> connective 
 %type <sym_tmpl> template
 %type <sym_func> function
+%type <sym_form> proof_form
+%type <sym_cases> case_list
 
 %type <sym_paramarr> type_assertion_list
 
-%type <sym_expr> expression logical_and_expression logical_or_expression negated_expression simple_expression constant_expression
+%type <sym_expr> expression logical_and_expression logical_or_expression negated_expression equality_expression simple_expression constant_expression
 %type <proof> expression_list
 
 %type <sym_exprarr> argument_list
 %type <sym_pfexpr> postfix_expresion
-%type <sym_pfexpr_ptr> justification
+%type <sym_just> justification citation_list
+%type <sym_expr> citation
 
 /* primary */
 %token <s> tkIdentifier tkConstant tkFalse tkTrue
@@ -59,11 +62,39 @@
 %token <s> tkLt tkGt tkEq tkNe tkAnd tkOr tkEqv tkImpl tkFllw
 
 /* keywords */ 
-%token <s> tkTmpl tkFunc tkTerm
+%token <s> tkTmpl tkFunc tkTerm tkMod tkImport tkExport
+
+/* keywords of proof forms, which are otherwise identifiers */
+%token <s> tkInduction tkOn tkUsing tkCases tkContradiction tkAssume tkCase
+
+/* a case ends where the next begins, rather than taking `case' for the
+ * identifier that starts another of its steps */
+%left tkCase
+
+/* literals */
+%token <s> tkString
+
+/* emitted before the input of a Session, which may be an expression */
+%token tkRepl
+
+%start input
 
 %%
+input
+	: statement_list
+	| tkRepl repl_input
+	;
+
+repl_input
+	: statement_list
+	| statement
+	| expression ';'	{ yylex.(*lexer).evaluate($1) }
+	| expression		{ yylex.(*lexer).evaluate($1) }
+	;
+
 statement_list
 	: statement ';' statement_list
+	| error ';' statement_list
 	| /* empty */
 	;
 
@@ -72,56 +103,45 @@ axiom
 	| /* empty */	{ $$ = false }
 	;
 
+export
+	: tkExport	{ $$ = true }
+	| /* empty */	{ $$ = false }
+	;
+
 statement
-	: axiom tkTmpl tkIdentifier template	{
-		$4.IsAxiom = $1
-		$4.Name = $3
-		sigma[$3] = $4
-		tbl, err := $4.Table()
-		if err != nil {
-			yylex.Error(err.Error())
-		}
-		fmt.Printf("%s: %s\n", $3, $4)
-		for _, prf := range $4.Proofs {
-			contextTbl := tbl.Nest(sigma)
-			proven := []string{}
-			for _, preprf := range prf.Preamble {
-				if err := sound(
-					preprf.Chain(), 
-					contextTbl,
-				); err != nil {
-					yylex.Error(
-						fmt.Sprintf(
-						"preamble error: %s", err),
-					)
-				}
-				burden, err := preprf.Burden()
-				if err != nil {
-					yylex.Error(
-						fmt.Sprintf(
-						"burden error: %s", err),
-					)
-				}
-				if l := preprf.Label(); l != "" {
-					contextTbl[l] = symbol.LocalProof{burden}
-					proven = append(proven, l)
-				}
-			}
-			err := examineProof(
-				$4.E, prf.Proof.Chain(), proven, contextTbl,
-			)
-			if err != nil {
-				yylex.Error(err.Error())
-			}
-		}
+	: export axiom tkTmpl identifier template	{
+		$5.IsAxiom = $2
+		$5.Name = $4
+		$5.Span = statementSpan(
+			$1, $2, $<span>1, $<span>2, $<span>3, $<span>5,
+		)
+		yylex.(*lexer).declare($4, $5, $1)
+		yylex.(*lexer).verifyTemplate($5)
+	}
+	| export axiom tkFunc identifier function	{
+		$5.IsAxiom = $2
+		$5.Name = $4
+		$5.Span = statementSpan(
+			$1, $2, $<span>1, $<span>2, $<span>3, $<span>5,
+		)
+		$5 = yylex.(*lexer).checkDefinition($5)
+		yylex.(*lexer).declare($4, $5, $1)
+		yylex.(*lexer).functions = append(yylex.(*lexer).functions, $5)
 	}
-	| axiom tkFunc tkIdentifier function	{
-		$4.IsAxiom = $1
-		$4.Name = $3
-		sigma[$3] = $4
+	| export tkTerm value type {
+		yylex.(*lexer).declare($3, $4, $1)
+		yylex.(*lexer).terms = append(yylex.(*lexer).terms, Term{
+			Name: $3, Type: $4,
+			Span: statementSpan(
+				$1, false, $<span>1, $<span>2, $<span>2, $<span>4,
+			),
+		})
 	}
-	| tkTerm value type {
-		sigma[$2] = symbol.Type($3)
+	| tkMod identifier {
+		yylex.(*lexer).declareModule($2, diag.Join($<span>1, $<span>2))
+	}
+	| tkImport tkString {
+		yylex.(*lexer).importModule($2, diag.Join($<span>1, $<span>2))
 	}
 	;
 
@@ -132,6 +152,7 @@ template
 			E:	$5,
 			Proofs:	[]symbol.ProofChain{},
 		}
+		$<span>$ = diag.Join($<span>1, $<span>6)
 	}
 	| '(' ')' '{' expression '}'				{
 		$$ = symbol.Template{
@@ -139,36 +160,81 @@ template
 			E:	$4,
 			Proofs:	[]symbol.ProofChain{},
 		}
+		$<span>$ = diag.Join($<span>1, $<span>5)
 	}
 	| template '{' expression_list '}'			{
-		preambleLen := len($3) - 1
-		if preambleLen < 0 {
-			yylex.Error("empty proof")
+		$$ = $1
+		$<span>$ = diag.Join($<span>1, $<span>4)
+		prf, err := proofChain($3, diag.Join($<span>2, $<span>4))
+		if err != nil {
+			yylex.(*lexer).report(diag.From(err, diag.Proof, $<span>2))
+			break
 		}
-		preamble := make([]symbol.Proof, preambleLen)
-		for i, just := range $3[:preambleLen] {
-			var prf symbol.Proof
-			if λ, ok := just.expr.(symbol.LambdaExpr); ok {
-				prf = symbol.LambdaProof{λ, just.label}
-			} else if expr, ok := just.expr.(symbol.JustifiableBinaryOpExpr); ok {
-				prf = symbol.LabelledChain{
-					expr.Quantise(), just.label,
-				}
-			}
-			preamble[i] = prf
+		$$.Proofs = append($$.Proofs, *prf)
+	}
+	| template proof_form					{
+		$$ = $1
+		$<span>$ = diag.Join($<span>1, $<span>2)
+		if $2 == nil {
+			break
+		}
+		$$.Proofs = append($$.Proofs, symbol.ProofChain{
+			Form: $2, Span: $<span>2,
+		})
+	}
+	;
+
+proof_form
+	: tkInduction tkOn identifier tkUsing identifier '{' expression_list '}' {
+		$<span>$ = diag.Join($<span>1, $<span>8)
+		ind, err := induction(
+			$3, $5, $7, diag.Join($<span>4, $<span>5), $<span>$,
+		)
+		if err != nil {
+			yylex.(*lexer).report(diag.From(err, diag.Proof, $<span>$))
+			$$ = nil
+			break
 		}
-		proper, ok := $3[len($3)-1].expr.(symbol.JustifiableBinaryOpExpr)
-		if !ok {
-			yylex.Error("non bop proof")
+		$$ = *ind
+	}
+	| tkCases '{' case_list '}' {
+		$<span>$ = diag.Join($<span>1, $<span>4)
+		$$ = symbol.Cases{Cases: $3, Span: $<span>$}
+	}
+	| tkContradiction '{' expression_list '}' {
+		$<span>$ = diag.Join($<span>1, $<span>4)
+		prf, err := proofChain($3, diag.Join($<span>2, $<span>4))
+		if err != nil {
+			yylex.(*lexer).report(diag.From(err, diag.Proof, $<span>2))
+			$$ = nil
+			break
 		}
-		$$ = symbol.Template{
-			Params:	$$.Params,
-			E: 	$$.E,
-			Proofs:	append($$.Proofs, symbol.ProofChain{
-				preamble, proper.Quantise(),
-			}),
+		$$ = symbol.Contradiction{Proof: *prf, Span: $<span>$}
+	}
+	| tkAssume expression '{' expression_list '}' {
+		$<span>$ = diag.Join($<span>1, $<span>5)
+		$$ = yylex.(*lexer).assumption(symbol.Hypothesis, $2, $4,
+			$<span>$, diag.Join($<span>3, $<span>5))
+	}
+	| tkAssume identifier ':' expression '{' expression_list '}' {
+		$<span>$ = diag.Join($<span>1, $<span>7)
+		$$ = yylex.(*lexer).assumption($2, $4, $6,
+			$<span>$, diag.Join($<span>5, $<span>7))
+	}
+	;
+
+case_list
+	: case_list tkCase expression ':' expression_list %prec tkCase {
+		$$ = $1
+		s := diag.Join($<span>2, $5[len($5)-1].expr.Extent())
+		prf, err := proofChain($5, s)
+		if err != nil {
+			yylex.(*lexer).report(diag.From(err, diag.Proof, s))
+			break
 		}
+		$$ = append($$, symbol.Case{Cond: $3, Proof: *prf, Span: s})
 	}
+	| /* empty */	{ $$ = []symbol.Case{} }
 	;
 
 function
@@ -176,34 +242,75 @@ function
 		$$ = symbol.Function{
 			Sig: symbol.FunctionSignature{
 				Params: $2,
-				Return: symbol.Type($4),
+				Return: $4,
 			},
 		}
+		$<span>$ = diag.Join($<span>1, $<span>4)
+	}
+	| '(' type_assertion_list ')' type '{' expression '}' {
+		$$ = symbol.Function{
+			Sig: symbol.FunctionSignature{
+				Params: $2,
+				Return: $4,
+			},
+			Body: $6,
+		}
+		$<span>$ = diag.Join($<span>1, $<span>7)
+	}
+	| '(' type_assertion_list ')' '{' expression '}' {
+		$$ = symbol.Function{
+			Sig: symbol.FunctionSignature{
+				Params: $2,
+				Return: symbol.Bool,
+			},
+			Body: $5,
+		}
+		$<span>$ = diag.Join($<span>1, $<span>6)
 	}
 	;
 
 type_assertion_list
-	: type_assertion_list ',' value type
-		{ $$ = append($1, symbol.Parameter{ Name: $3, Type: symbol.Type($4) }) }
-	| value type
-		{ $$ = []symbol.Parameter{symbol.Parameter{Name: $1, Type: symbol.Type($2)}}}
+	: type_assertion_list ',' value type	{
+		$$ = append($1, symbol.Parameter{ Name: $3, Type: $4 })
+		$<span>$ = diag.Join($<span>1, $<span>4)
+	}
+	| value type				{
+		$$ = []symbol.Parameter{symbol.Parameter{Name: $1, Type: $2}}
+		$<span>$ = diag.Join($<span>1, $<span>2)
+	}
 	;
 
 type
-	: tkIdentifier				{ $$ = $1 }
-	| tkFunc '(' value_list ')' type	{ 
-		$$ = fmt.Sprintf("func(%s) %s", strings.Join($3, ", "), $5)
+	: identifier				{ $$ = symbol.BaseType($1) }
+	| tkFunc '(' type_list ')' type		{
+		$$ = symbol.FuncType{Params: $3, Return: $5}
+		$<span>$ = diag.Join($<span>1, $<span>5)
 	}
+	| tkFunc '(' ')' type			{
+		$$ = symbol.FuncType{Params: []symbol.Type{}, Return: $4}
+		$<span>$ = diag.Join($<span>1, $<span>4)
+	}
+	;
+
+type_list
+	: type_list ',' type		{ $$ = append($1, $3) }
+	| type				{ $$ = []symbol.Type{$1} }
 	;
 
-value_list
-	: value_list ',' value		{ $$ = append($1, $3) }
-	| value				{ $$ = []string{$1} }
+identifier
+	: tkIdentifier
+	| tkInduction
+	| tkOn
+	| tkUsing
+	| tkCases
+	| tkContradiction
+	| tkAssume
+	| tkCase
 	;
 
 value
 	: tkConstant		{ $$ = $1 }
-	| tkIdentifier		{ $$ = $1 }
+	| identifier		{ $$ = $1 }
 	;
 
 expression
@@ -213,27 +320,41 @@ expression
 			Just: 		$3,
 		}
 	}
-	| type_assertion_list	
-		{ $$ = symbol.ConstantExpr(false) }
+	| type_assertion_list
+		{ $$ = typeAssertions($1, $<span>1) }
 	| logical_or_expression 
 	;
 
 expression_list
-	: expression_list expression_list
-		{ $$ = append($1, $2...) }
-	| tkIdentifier ':' expression ';' 
+	: expression_list identifier ':' expression ';'
+		{ $$ = append($1, labelledJust{$4, $2}) }
+	| expression_list expression ';'
+		{ $$ = append($1, labelledJust{$2, ""}) }
+	| identifier ':' expression ';' 
 		{ $$ = []labelledJust{labelledJust{$3, $1}} }
 	| expression ';' 
 		{ $$ = []labelledJust{labelledJust{$1, ""}} }
 	;
 
 justification
-	: '{' postfix_expresion '}'
-		{ var x = $2; $$ = &x }
+	: '{' citation_list '}'
+		{ $$ = $2 }
 	| /* empty */ 
 		{ $$ = nil }
 	;
 
+citation_list
+	: citation_list ',' citation	{ $$ = append($1, $3) }
+	| citation			{ $$ = symbol.Citations{$1} }
+	;
+
+citation
+	: postfix_expresion		{ $$ = $1 }
+	| identifier			{
+		$$ = symbol.SimpleExpr{Name: $1, Span: $<span>1}
+	}
+	;
+
 connective
 	: tkEqv		{ $$ = symbol.Eqv }
 	| tkImpl	{ $$ = symbol.Impl }
@@ -253,23 +374,68 @@ logical_and_expression
 	;
 
 negated_expression
-	: '!' negated_expression	{ $$ = symbol.NegatedExpr{$2} }
+	: '!' negated_expression	{
+		$$ = symbol.NegatedExpr{
+			Expr: $2, Span: diag.Join($<span>1, $2.Extent()),
+		}
+	}
+	| equality_expression
+	;
+
+equality_expression
+	: constant_expression tkEq constant_expression
+		{ $$ = symbol.EqualityExpr{Op: symbol.Eq, E1: $1, E2: $3} }
+	| constant_expression tkNe constant_expression
+		{ $$ = symbol.EqualityExpr{Op: symbol.Ne, E1: $1, E2: $3} }
 	| constant_expression
 	;
 
 constant_expression
-	: tkTrue			{ $$ = symbol.ConstantExpr(true) }
-	| tkFalse			{ $$ = symbol.ConstantExpr(false) }
-	| '(' expression ')'		{ $$ = symbol.BracketedExpr{$2} }
+	: tkTrue			{
+		$$ = symbol.ConstantExpr{Value: true, Span: $<span>1}
+	}
+	| tkFalse			{
+		$$ = symbol.ConstantExpr{Value: false, Span: $<span>1}
+	}
+	| '(' expression ')'		{
+		$$ = symbol.BracketedExpr{
+			Expr: $2, Span: diag.Join($<span>1, $<span>3),
+		}
+	}
 	| '(' type_assertion_list ')' '{' expression '}' { 
-		$$ = symbol.LambdaExpr{$2, $5} 
+		$$ = symbol.LambdaExpr{
+			Params: $2, Expr: $5,
+			Span: diag.Join($<span>1, $<span>6),
+		}
+	}
+	| '[' type_assertion_list ']' '{' expression '}' {
+		$$ = symbol.ExistentialExpr{
+			Params: $2, Expr: $5,
+			Span: diag.Join($<span>1, $<span>6),
+		}
+	}
+	| '[' type_assertion_list ']' type_assertion_list {
+		$$ = symbol.ExistentialExpr{
+			Params: $2, Expr: typeAssertions($4, $<span>4),
+			Span: diag.Join($<span>1, $<span>4),
+		}
+	}
+	| postfix_expresion type	{
+		$$ = symbol.TypeAssertionExpr{
+			Expr: $1, Type: $2,
+			Span: diag.Join($1.Span, $<span>2),
+		}
 	}
 	| simple_expression
 	;
 
 simple_expression
-	: tkIdentifier			{ $$ = symbol.SimpleExpr($1) }
-	| tkConstant			{ $$ = symbol.SimpleExpr($1) }
+	: identifier			{
+		$$ = symbol.SimpleExpr{Name: $1, Span: $<span>1}
+	}
+	| tkConstant			{
+		$$ = symbol.SimpleExpr{Name: $1, Span: $<span>1}
+	}
 	| postfix_expresion		{ $$ = symbol.Expr($1) }
 	;
 
@@ -279,9 +445,17 @@ argument_list
 	;
 
 postfix_expresion
-	: tkIdentifier '(' argument_list ')'
-		{ $$ = symbol.PostfixExpr{$1, $3} }
-	| tkIdentifier '(' ')'
-		{ $$ = symbol.PostfixExpr{$1, []symbol.Expr{}} }
+	: identifier '(' argument_list ')'	{
+		$$ = symbol.PostfixExpr{
+			Name: $1, Args: $3,
+			Span: diag.Join($<span>1, $<span>4),
+		}
+	}
+	| identifier '(' ')'			{
+		$$ = symbol.PostfixExpr{
+			Name: $1, Args: []symbol.Expr{},
+			Span: diag.Join($<span>1, $<span>3),
+		}
+	}
 	;
 %%
@@ -1,36 +1,557 @@
 package parser
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
 
+	"git.sr.ht/~lbnz/i2/internal/diag"
 	"git.sr.ht/~lbnz/i2/internal/symbol"
 	"git.sr.ht/~lbnz/i2/internal/truth"
 )
 
-func Verify(input string) {
-	sigma = symbol.Table{"1": symbol.Any}
-	if ret := yyParse(&lexer{[]rune(string(input)), 0}); ret != 0 {
-		fmt.Fprintf(os.Stderr, "exit code: %d", ret)
+// Outcome is the result of attempting to discharge a proof obligation.
+type Outcome int
+
+const (
+	// Skipped obligations were not attempted because of earlier failures.
+	Skipped Outcome = iota
+	Proven
+	Refuted
+	Undecided
+	// Invalid obligations could not be stated because their expressions
+	// failed analysis.
+	Invalid
+)
+
+func (o Outcome) String() string {
+	switch o {
+	case Skipped:
+		return "skipped"
+	case Proven:
+		return "proven"
+	case Refuted:
+		return "refuted"
+	case Undecided:
+		return "undecided"
+	case Invalid:
+		return "invalid"
+	default:
+		panic(fmt.Sprintf("unknown outcome %d", o))
 	}
 }
 
-func sound(prf symbol.RelationChain, tbl symbol.Table) error {
-	for _, expr := range prf {
-		fmt.Printf("\t%s\n", expr)
-		aExpr, err := expr.Analyse(tbl)
+func (o Outcome) MarshalText() ([]byte, error) {
+	return []byte(o.String()), nil
+}
+
+// Obligation records the attempt to discharge a proof obligation.
+type Obligation struct {
+	Outcome
+	Duration time.Duration
+
+	// Diagnostic explains the failure of the obligation, if it failed.
+	Diagnostic *diag.Diagnostic
+}
+
+// Step is the obligation that a single link of a RelationChain holds.
+type Step struct {
+	Expr symbol.JustifiableBinaryOpExpr
+
+	// P is the Proposition the step analyses to, under the hypothesis
+	// that the variables in scope are of their types, or nil if it is
+	// Invalid.
+	P truth.Proposition
+	Obligation
+}
+
+// Lemma is a proof from the preamble of a ProofChain.
+type Lemma struct {
+	Proof symbol.Proof
+	Steps []Step
+}
+
+// ProofResult records the verification of a ProofChain: its preamble, the
+// steps of the proof proper and the burden that they prove the assertion.
+// The proof of a ProofChain with a Form is instead divided into Subproofs.
+type ProofResult struct {
+	Chain     symbol.ProofChain
+	Preamble  []Lemma
+	Steps     []Step
+	Subproofs []Subproof
+	QED       Obligation
+}
+
+// TemplateResult records the verification of the proofs of a template.
+type TemplateResult struct {
+	Template symbol.Template
+	Proofs   []ProofResult
+}
+
+// Term is a `term' statement, declaring the type of a constant.
+type Term struct {
+	Name string
+	Type symbol.Type
+	diag.Span
+}
+
+// Import is a symbol bound by an import statement.
+type Import struct {
+	// Name is that by which the input refers to the symbol, qualified by
+	// the name of its module or not.
+	Name string
+
+	// Symbol is as declared by the module, its names unqualified.
+	Symbol symbol.Scope
+
+	// Module is that declaring the symbol, in the statement of its file
+	// at Span.
+	Module *Module
+	diag.Span
+}
+
+// Result is the outcome of verifying a source file: every function, term and
+// template in the order of its statement, the symbols imported, and the
+// problems found throughout.
+type Result struct {
+	Functions   []symbol.Function
+	Terms       []Term
+	Templates   []TemplateResult
+	Imports     []Import
+	Diagnostics []diag.Diagnostic
+
+	// Hints are those found for the steps on Config.HintLine.
+	Hints []Hint
+
+	// Fills are the results of the search for the failing steps on the
+	// line of Config.Search.
+	Fills []Fill
+
+	// Scripts are the SMT-LIB 2 scripts of the steps, if Config.SMTLIB.
+	Scripts []Script
+}
+
+// Config configures Verify.
+type Config struct {
+	// Timeout bounds each proof obligation.
+	Timeout time.Duration
+
+	// File names the input, against which its imports are resolved.
+	File string
+
+	// Importer resolves imports. If it is nil, imports are errors.
+	Importer Importer
+
+	// Lint enables the reporting of unnecessary citations, unused preamble
+	// proofs and axioms, and steps that may be merged.
+	Lint bool
+
+	// HintLine, if nonzero, is the line of the steps for which to seek
+	// citations that justify them, if they are unjustified or fail.
+	HintLine int
+
+	// Search, if not nil, configures the search for chains that fill the
+	// gaps of the failing steps on a line.
+	Search *Search
+
+	// SMTLIB enables the translation of every step verified into an
+	// SMT-LIB 2 script.
+	SMTLIB bool
+}
+
+// Verify parses and verifies input. The error is that of ctx if it ends
+// before verification does.
+func Verify(ctx context.Context, input string, cfg Config) (*Result, error) {
+	l := newLexer(input)
+	l.verifier = &verifier{ctx, cfg.Timeout}
+	l.importer = cfg.Importer
+	if cfg.File != "" {
+		l.chain = []string{absolute(cfg.File)}
+	}
+	if cfg.Lint {
+		l.linter = newLinter()
+	}
+	l.hintLine = cfg.HintLine
+	l.search = cfg.Search
+	l.smtlib = cfg.SMTLIB
+	yyParse(l)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if l.linter != nil {
+		l.lintAxioms()
+	}
+	return l.result(), nil
+}
+
+// verifier discharges proof obligations.
+type verifier struct {
+	ctx     context.Context
+	timeout time.Duration
+}
+
+// decide decides p within the timeout, recording the attempt.
+func (v *verifier) decide(p truth.Proposition) (bool, time.Duration, error) {
+	ctx, cancel := context.WithTimeout(v.ctx, v.timeout)
+	defer cancel()
+	start := time.Now()
+	outcome, err := truth.DecideContext(ctx, p)
+	return outcome, time.Since(start), err
+}
+
+// typed returns p under the hypothesis that the variables and constants of
+// tbl are of their types.
+func typed(p truth.Proposition, tbl symbol.Table) truth.Proposition {
+	if typing := symbol.Typing(tbl); typing != nil {
+		return truth.Impl(typing, p)
+	}
+	return p
+}
+
+// failed returns the Obligation failing with d.
+func failed(d diag.Diagnostic, dur time.Duration) Obligation {
+	o := Obligation{Outcome: Refuted, Duration: dur, Diagnostic: &d}
+	switch d.Code {
+	case diag.Undecided:
+		o.Outcome = Undecided
+	case diag.Analysis, diag.Proof:
+		o.Outcome = Invalid
+	}
+	return o
+}
+
+// diagnostics returns the diagnostics of the failed steps.
+func diagnostics(steps []Step) []diag.Diagnostic {
+	var diags []diag.Diagnostic
+	for _, s := range steps {
+		if s.Diagnostic != nil {
+			diags = append(diags, *s.Diagnostic)
+		}
+	}
+	return diags
+}
+
+// statementSpan returns the span of a statement from its optional export
+// and axiom markers, its keyword and its body.
+func statementSpan(export, axiom bool, exp, at, keyword, body diag.Span) diag.Span {
+	switch {
+	case export:
+		return diag.Join(exp, body)
+	case axiom:
+		return diag.Join(at, body)
+	default:
+		return diag.Join(keyword, body)
+	}
+}
+
+// typeAssertions returns the conjunction of the assertions that each of
+// params is of its type.
+func typeAssertions(params []symbol.Parameter, s diag.Span) symbol.Expr {
+	var e symbol.Expr
+	for _, p := range params {
+		ta := symbol.TypeAssertionExpr{
+			Expr: symbol.SimpleExpr{Name: p.Name, Span: s},
+			Type: p.Type,
+			Span: s,
+		}
+		if e == nil {
+			e = ta
+		} else {
+			e = symbol.BinaryOpExpr{Op: symbol.And, E1: e, E2: ta}
+		}
+	}
+	return e
+}
+
+// proofChain assembles the expressions of a proof block into a ProofChain:
+// all but the last are the preamble, and the last is the proof proper.
+func proofChain(exprs []labelledJust, s diag.Span) (*symbol.ProofChain, error) {
+	if len(exprs) == 0 {
+		return nil, diag.Errorf(diag.Proof, s, "empty proof")
+	}
+	preambleLen := len(exprs) - 1
+	preamble := make([]symbol.Proof, preambleLen)
+	for i, just := range exprs[:preambleLen] {
+		switch e := just.expr.(type) {
+		case symbol.LambdaExpr:
+			if _, ok := e.Expr.(symbol.JustifiableBinaryOpExpr); !ok {
+				return nil, diag.Errorf(diag.Proof, e.Span,
+					"preamble proof `%s' is not a relation", e)
+			}
+			preamble[i] = symbol.LambdaProof{E: e, L: just.label}
+		case symbol.JustifiableBinaryOpExpr:
+			preamble[i] = symbol.LabelledChain{
+				RelationChain: e.Quantise(), L: just.label,
+			}
+		default:
+			return nil, diag.Errorf(diag.Proof, e.Extent(),
+				"preamble proof `%s' is not a relation", e)
+		}
+	}
+	last := exprs[preambleLen].expr
+	proper, ok := last.(symbol.JustifiableBinaryOpExpr)
+	if !ok {
+		return nil, diag.Errorf(diag.Proof, last.Extent(),
+			"proof `%s' is not a relation", last)
+	}
+	return &symbol.ProofChain{
+		Preamble: preamble, Proof: proper.Quantise(), Span: s,
+	}, nil
+}
+
+// checkDefinition checks the body of f, reporting the problems found. A body
+// that cannot be unfolded is dropped, leaving f undefined.
+func (l *lexer) checkDefinition(f symbol.Function) symbol.Function {
+	if err := f.CheckDefinition(l.sigma); err != nil {
+		l.report(diag.From(err, diag.Analysis, f.Span))
+		f.Body = nil
+	}
+	return f
+}
+
+// verifyTemplate checks the proofs of tmpl, reporting the problems found.
+func (l *lexer) verifyTemplate(tmpl symbol.Template) {
+	result := TemplateResult{Template: tmpl}
+	l.steps = 0
+	defer func() { l.templates = append(l.templates, result) }()
+	tbl, err := tmpl.Table()
+	if err != nil {
+		l.report(diag.From(err, diag.Analysis, tmpl.Span))
+		return
+	}
+	for _, prf := range tmpl.Proofs {
+		if prf.Form != nil {
+			result.Proofs = append(result.Proofs,
+				l.verifyForm(tmpl, prf, tbl))
+			continue
+		}
+		result.Proofs = append(result.Proofs,
+			l.verifyChain(tmpl.E, prf, tbl))
+	}
+}
+
+// verifyChain checks that prf proves assertion in the context of tbl,
+// reporting the problems found.
+func (l *lexer) verifyChain(assertion symbol.Expr, prf symbol.ProofChain,
+	tbl symbol.Table) ProofResult {
+	contextTbl := tbl.Nest(l.sigma)
+	proven := []string{}
+	presult := ProofResult{Chain: prf}
+	sound := true
+	for _, preprf := range prf.Preamble {
+		steps := l.sound(preprf.Chain(), contextTbl)
+		presult.Preamble = append(presult.Preamble,
+			Lemma{Proof: preprf, Steps: steps})
+		diags := diagnostics(steps)
+		l.diags = append(l.diags, diags...)
+		sound = sound && len(diags) == 0
+		burden, err := preprf.Burden()
 		if err != nil {
-			return fmt.Errorf("analysis error: %s", err)
+			l.report(diag.From(err, diag.Burden, preprf.Extent()))
+			sound = false
+			continue
+		}
+		if lbl := preprf.Label(); lbl != "" {
+			contextTbl[lbl] = symbol.LocalProof{Expr: burden}
+			proven = append(proven, lbl)
+		}
+	}
+	if sound {
+		presult.Steps, presult.QED = l.examineProof(
+			assertion, prf.Proof.Chain(), proven, contextTbl,
+		)
+	} else {
+		// the proof rests on the lemmas, so it stands only if they do
+		presult.Steps = l.sound(prf.Proof.Chain(), contextTbl)
+		presult.QED = Obligation{Outcome: Skipped}
+	}
+	l.diags = append(l.diags, diagnostics(presult.Steps)...)
+	if presult.QED.Diagnostic != nil {
+		l.diags = append(l.diags, *presult.QED.Diagnostic)
+	}
+	if l.linter != nil {
+		l.lintChain(prf, presult, contextTbl)
+	}
+	if l.hintLine > 0 {
+		for _, lemma := range presult.Preamble {
+			l.hintSteps(lemma.Steps, contextTbl)
 		}
-		outcome, err := truth.Decide(aExpr.P)
+		l.hintSteps(presult.Steps, contextTbl)
+	}
+	if l.search != nil {
+		for _, lemma := range presult.Preamble {
+			l.fillSteps(lemma.Steps, contextTbl)
+		}
+		l.fillSteps(presult.Steps, contextTbl)
+	}
+	if l.smtlib {
+		for _, lemma := range presult.Preamble {
+			l.exportSteps(lemma.Steps, contextTbl)
+		}
+		l.exportSteps(presult.Steps, contextTbl)
+	}
+	return presult
+}
+
+// valuation is a named Proposition whose value is reported alongside a
+// counterexample.
+type valuation struct {
+	name string
+	P    truth.Proposition
+}
+
+// counterexample describes a state of the atoms in which a proof obligation
+// is false.
+type counterexample struct {
+	what   string
+	state  truth.State
+	atoms  map[truth.Variable]symbol.Expr
+	values []valuation
+}
+
+func (c *counterexample) Error() string {
+	return fmt.Sprintf("%s is false", c.what)
+}
+
+// notes describe the falsifying state and the values it gives to the
+// Propositions of interest.
+func (c *counterexample) notes() []string {
+	atoms := make([][]string, len(c.state))
+	for i, v := range c.state.Atoms() {
+		name := string(v)
+		if e, ok := c.atoms[v]; ok {
+			name = e.String()
+		}
+		atoms[i] = []string{name, fmt.Sprintf(":= %t", c.state[v])}
+	}
+	values := make([][]string, len(c.values))
+	for i, v := range c.values {
+		values[i] = []string{
+			v.name, fmt.Sprintf("`%s'", v.P),
+			fmt.Sprintf(":= %t", c.state.Eval(v.P)),
+		}
+	}
+	notes := []string{"when\n" + strings.TrimSuffix(table(atoms), "\n")}
+	if len(values) > 0 {
+		notes = append(notes,
+			"giving\n"+strings.TrimSuffix(table(values), "\n"))
+	}
+	return notes
+}
+
+// table lays out rows in indented, aligned columns.
+func table(rows [][]string) string {
+	if len(rows) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	w := tabwriter.NewWriter(&b, 0, 8, 1, ' ', 0)
+	for _, row := range rows {
+		fmt.Fprintf(w, "%s\n", strings.Join(row, "\t"))
+	}
+	w.Flush()
+	lines := strings.SplitAfter(b.String(), "\n")
+	return "\t" + strings.Join(lines[:len(lines)-1], "\t")
+}
+
+// falsify returns a counterexample to p, or the error otherwise explaining
+// why p could not be decided. If the decision gave up, the state falsifying
+// p with its quantified subformulas treated as opaque is no counterexample,
+// and none is sought.
+func falsify(what string, p truth.Proposition, decErr error,
+	atoms map[truth.Variable]symbol.Expr, values []valuation) error {
+	if errors.Is(decErr, truth.ErrUnknown) {
+		return fmt.Errorf("%s is undecided: %w", what, decErr)
+	}
+	state, err := truth.Falsify(p)
+	if err != nil || state == nil {
+		if decErr != nil {
+			return fmt.Errorf("decision error: %w", decErr)
+		}
+		return fmt.Errorf("contradiction")
+	}
+	return &counterexample{what, state, atoms, values}
+}
+
+// obligationDiagnostic presents err, found discharging the obligation at s,
+// as a Diagnostic.
+func obligationDiagnostic(err error, code diag.Code, s diag.Span) diag.Diagnostic {
+	var c *counterexample
+	if errors.As(err, &c) {
+		return diag.Diagnostic{
+			Severity: diag.Error,
+			Code:     code,
+			Span:     s,
+			Message:  c.Error(),
+			Notes:    c.notes(),
+		}
+	}
+	if errors.Is(err, truth.ErrUnknown) {
+		d := diag.From(err, diag.Undecided, s)
+		d.Severity = diag.Warning
+		return d
+	}
+	return diag.From(err, code, s)
+}
+
+func stepValuations(expr symbol.JustifiableBinaryOpExpr,
+	tbl symbol.Table) []valuation {
+	values := []valuation{}
+	if e1, err := expr.E1.Analyse(tbl); err == nil {
+		values = append(values, valuation{"E1", e1.P})
+	}
+	if e2, err := expr.E2.Analyse(tbl); err == nil {
+		values = append(values, valuation{"E2", e2.P})
+	}
+	if expr.Just != nil {
+		if just, err := expr.Justification(tbl); err == nil {
+			values = append(values, valuation{expr.Just.String(), just})
+		}
+	}
+	return values
+}
+
+func (v *verifier) sound(prf symbol.RelationChain, tbl symbol.Table) []Step {
+	steps := make([]Step, len(prf))
+	for i, expr := range prf {
+		steps[i].Expr = expr
+		aExpr, err := expr.Analyse(tbl)
 		if err != nil {
-			return fmt.Errorf("decision error: %s", err)
+			steps[i].Obligation = failed(
+				diag.From(err, diag.Analysis, expr.Extent()), 0,
+			)
+			continue
 		}
-		if !outcome {
-			return fmt.Errorf("contradiction")
+		steps[i].P = typed(aExpr.P, tbl)
+		outcome, dur, err := v.decide(steps[i].P)
+		if err == nil && outcome {
+			steps[i].Obligation = Obligation{Outcome: Proven, Duration: dur}
+			continue
 		}
+		steps[i].Obligation = failed(obligationDiagnostic(falsify(
+			fmt.Sprintf("step %d `%s'", i+1, expr),
+			steps[i].P, err,
+			symbol.Atoms(expr, tbl), stepValuations(expr, tbl),
+		), diag.Unsound, expr.Extent()), dur)
 	}
-	return nil
+	return steps
+}
+
+// holds indicates whether the relation e, justified by just, is decided to
+// hold in tbl.
+func (v *verifier) holds(e symbol.BinaryOpExpr, just symbol.Citations,
+	tbl symbol.Table) bool {
+	aExpr, err := symbol.JustifiableBinaryOpExpr{
+		BinaryOpExpr: e, Just: just,
+	}.Analyse(tbl)
+	if err != nil {
+		return false
+	}
+	outcome, _, err := v.decide(typed(aExpr.P, tbl))
+	return err == nil && outcome
 }
 
 func getProofProp(A, B truth.Proposition, op symbol.Operator) truth.Proposition {
@@ -42,52 +563,64 @@ func getProofProp(A, B truth.Proposition, op symbol.Operator) truth.Proposition
 	}
 }
 
-func examineProof(assertion symbol.Expr, prf symbol.RelationChain,
-	provenLabels []string, tbl symbol.Table) error {
-	fmt.Printf("proof:\n")
+func (v *verifier) examineProof(assertion symbol.Expr, prf symbol.RelationChain,
+	provenLabels []string, tbl symbol.Table) ([]Step, Obligation) {
 	// confirm links are valid
-	if err := sound(prf, tbl); err != nil {
-		return err
+	steps := v.sound(prf, tbl)
+	if len(diagnostics(steps)) > 0 {
+		return steps, Obligation{Outcome: Skipped}
 	}
 	// confirm first and last term joined by appropriate connective imply
 	// the asserted proposition
 	op, err := prf.GCFOperator()
 	if err != nil {
-		return err
+		return steps, failed(diag.From(err, diag.Proof, prf.Extent()), 0)
 	}
 	provenTbl := symbol.Table{}
 	for k, v := range tbl {
 		provenTbl[k] = v
 	}
 	for _, v := range provenLabels {
-		provenTbl[v] = symbol.LocalProof{symbol.ConstantExpr(true)}
+		provenTbl[v] = symbol.LocalProof{
+			Expr: symbol.ConstantExpr{Value: true},
+		}
 	}
 	first, err := prf[0].E1.Analyse(provenTbl)
 	if err != nil {
-		return err
+		return steps, failed(
+			diag.From(err, diag.Analysis, prf[0].E1.Extent()), 0,
+		)
 	}
 	second, err := prf[len(prf)-1].E2.Analyse(provenTbl)
 	if err != nil {
-		return err
+		return steps, failed(
+			diag.From(err, diag.Analysis, prf[len(prf)-1].E2.Extent()), 0,
+		)
 	}
 	proofProp := getProofProp(first.P, second.P, op)
 	assertionP, err := assertion.Analyse(tbl)
 	if err != nil {
-		return err
+		return steps, failed(
+			diag.From(err, diag.Analysis, assertion.Extent()), 0,
+		)
 	}
-	qed := truth.Impl(proofProp, assertionP.P)
-	outcome, err := truth.Decide(qed)
-	if err != nil {
-		fmt.Println("first", prf[0].E1)
-		fmt.Println("prf", prf)
-		fmt.Println("assertion", assertionP.P)
-		fmt.Println("proof", proofProp)
-		fmt.Println("qed was", qed)
-		return fmt.Errorf("qed burden failure: %s", err)
-	}
-	if !outcome {
-		return fmt.Errorf("contradiction")
+	qed := typed(truth.Impl(proofProp, assertionP.P), tbl)
+	outcome, dur, err := v.decide(qed)
+	if err != nil || !outcome {
+		atoms := symbol.Atoms(assertion, tbl)
+		for k, v := range symbol.Atoms(prf[0].E1, provenTbl) {
+			atoms[k] = v
+		}
+		for k, v := range symbol.Atoms(prf[len(prf)-1].E2, provenTbl) {
+			atoms[k] = v
+		}
+		return steps, failed(obligationDiagnostic(falsify(
+			"qed burden `proven ==> assertion'", qed, err, atoms,
+			[]valuation{
+				{"proven", proofProp},
+				{"assertion", assertionP.P},
+			},
+		), diag.QED, prf.Extent()), dur)
 	}
-	fmt.Println("qed")
-	return nil
+	return steps, Obligation{Outcome: Proven, Duration: dur}
 }
@@ -0,0 +1,264 @@
+// Package repl implements an interactive session with the verifier, in which
+// declarations are entered one at a time and expressions evaluated against
+// them.
+package repl
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"git.sr.ht/~lbnz/i2/internal/diag"
+	"git.sr.ht/~lbnz/i2/internal/parser"
+	"git.sr.ht/~lbnz/i2/internal/symbol"
+	"git.sr.ht/~lbnz/i2/internal/truth"
+)
+
+const (
+	prompt       = "i2> "
+	continuation = "... "
+)
+
+const help = `Enter declarations (tmpl, func, term) ending in ';', or an expression to
+evaluate. A relation such as
+	eq(a, b) ==> { injectivity(a, b) } eq(succ(a), succ(b))
+is evaluated step by step, as in a proof; it continues onto the next line if
+one ends in a connective.
+Commands:
+	:type NAME	show the type of a symbol
+	:show NAME	show the declaration of a symbol
+	:undo		revert the last declarations
+	:help		show this message
+	:quit		end the session`
+
+// REPL is an interactive session writing its output to w.
+type REPL struct {
+	s *parser.Session
+	w io.Writer
+}
+
+// New returns a REPL resolving imports with importer.
+func New(ctx context.Context, w io.Writer, importer parser.Importer) *REPL {
+	s := parser.NewSession(ctx, truth.DefaultTimeout)
+	s.Importer = importer
+	return &REPL{s, w}
+}
+
+// Load executes the contents of file, reporting only its problems and what
+// it declares.
+func (r *REPL) Load(file, source string) {
+	in := r.s.Load(file, source)
+	diag.Fprint(r.w, source, in.Diagnostics)
+	fmt.Fprintf(r.w, "loaded %d functions, %d terms and %d templates\n",
+		len(in.Functions), len(in.Terms), len(in.Templates))
+}
+
+// Run reads lines from in until it ends or the session is quit.
+func (r *REPL) Run(in io.Reader) error {
+	sc := bufio.NewScanner(in)
+	var buf strings.Builder
+	fmt.Fprint(r.w, prompt)
+	for sc.Scan() {
+		line := sc.Text()
+		if buf.Len() == 0 && strings.HasPrefix(strings.TrimSpace(line), ":") {
+			if !r.command(strings.TrimSpace(line)) {
+				return nil
+			}
+			fmt.Fprint(r.w, prompt)
+			continue
+		}
+		buf.WriteString(line)
+		buf.WriteString("\n")
+		if !complete(buf.String()) {
+			fmt.Fprint(r.w, continuation)
+			continue
+		}
+		if input := buf.String(); strings.TrimSpace(input) != "" {
+			r.exec(input)
+		}
+		buf.Reset()
+		fmt.Fprint(r.w, prompt)
+	}
+	return sc.Err()
+}
+
+// complete indicates whether input may be executed: its brackets must be
+// balanced, and statements must end in a semicolon while expressions must
+// not end in a connective.
+func complete(input string) bool {
+	depth := 0
+	for _, c := range input {
+		switch c {
+		case '(', '{':
+			depth++
+		case ')', '}':
+			depth--
+		}
+	}
+	if depth > 0 {
+		return false
+	}
+	s := strings.TrimSpace(input)
+	for _, kw := range []string{"@", "tmpl", "func", "term"} {
+		if strings.HasPrefix(s, kw) {
+			return strings.HasSuffix(s, ";")
+		}
+	}
+	for _, conn := range []string{"===", "==>", "<=="} {
+		if strings.HasSuffix(s, conn) {
+			return false
+		}
+	}
+	return true
+}
+
+// command executes a command, returning false if it quits the session.
+func (r *REPL) command(line string) bool {
+	fields := strings.Fields(line)
+	arg := func() (string, bool) {
+		if len(fields) != 2 {
+			fmt.Fprintf(r.w, "usage: %s NAME\n", fields[0])
+			return "", false
+		}
+		return fields[1], true
+	}
+	switch fields[0] {
+	case ":type", ":t":
+		if name, ok := arg(); ok {
+			r.lookup(name, typeOf)
+		}
+	case ":show", ":s":
+		if name, ok := arg(); ok {
+			r.lookup(name, show)
+		}
+	case ":undo", ":u":
+		if r.s.Undo() {
+			fmt.Fprintln(r.w, "undone")
+		} else {
+			fmt.Fprintln(r.w, "nothing to undo")
+		}
+	case ":help", ":h", ":?":
+		fmt.Fprintln(r.w, help)
+	case ":quit", ":q":
+		return false
+	default:
+		fmt.Fprintf(r.w, "unknown command %s (try :help)\n", fields[0])
+	}
+	return true
+}
+
+func (r *REPL) lookup(name string, f func(string, symbol.Scope) string) {
+	sym, ok := r.s.Lookup(name)
+	if !ok {
+		fmt.Fprintf(r.w, "`%s' not declared\n", name)
+		return
+	}
+	fmt.Fprintln(r.w, f(name, sym))
+}
+
+func typeOf(name string, sym symbol.Scope) string {
+	switch sym := sym.(type) {
+	case symbol.Type:
+		return fmt.Sprintf("%s %s", name, sym)
+	case symbol.QualifiedTerm:
+		return fmt.Sprintf("%s %s", name, sym.Type)
+	case symbol.Function:
+		return fmt.Sprintf("%s func(%s) %s",
+			name, params(sym.Sig.Params), sym.Sig.Return)
+	case symbol.Template:
+		return fmt.Sprintf("%s tmpl(%s)", name, params(sym.Params))
+	default:
+		return fmt.Sprintf("%s %T", name, sym)
+	}
+}
+
+func show(name string, sym symbol.Scope) string {
+	switch sym := sym.(type) {
+	case symbol.Type:
+		return fmt.Sprintf("term %s %s;", name, sym)
+	case symbol.QualifiedTerm:
+		return fmt.Sprintf("term %s %s;", name, sym.Type)
+	case symbol.Function:
+		if sym.Body != nil {
+			return fmt.Sprintf("%sfunc %s(%s) %s { %s };",
+				axiom(sym.IsAxiom), name, params(sym.Sig.Params),
+				sym.Sig.Return, sym.Body)
+		}
+		return fmt.Sprintf("%sfunc %s(%s) %s;",
+			axiom(sym.IsAxiom), name, params(sym.Sig.Params), sym.Sig.Return)
+	case symbol.Template:
+		return fmt.Sprintf("%stmpl %s(%s) { %s }; /* %d proofs */",
+			axiom(sym.IsAxiom), name, params(sym.Params), sym.E,
+			len(sym.Proofs))
+	default:
+		return typeOf(name, sym)
+	}
+}
+
+func axiom(is bool) string {
+	if is {
+		return "@"
+	}
+	return ""
+}
+
+func params(arr []symbol.Parameter) string {
+	s := make([]string, len(arr))
+	for i, p := range arr {
+		s[i] = fmt.Sprintf("%s %s", p.Name, p.Type)
+	}
+	return strings.Join(s, ", ")
+}
+
+// exec executes input, showing what it declares or evaluates to.
+func (r *REPL) exec(input string) {
+	in := r.s.Exec(input)
+	for _, f := range in.Functions {
+		fmt.Fprintln(r.w, typeOf(f.Name, f))
+	}
+	for _, t := range in.Terms {
+		fmt.Fprintln(r.w, typeOf(t.Name, t.Type))
+	}
+	for _, t := range in.Templates {
+		fmt.Fprintln(r.w, typeOf(t.Template.Name, t.Template))
+		for i, prf := range t.Proofs {
+			fmt.Fprintf(r.w, "\tproof %d: %s\n", i+1, qed(prf.QED))
+		}
+	}
+	if ev := in.Evaluation; ev != nil {
+		if ev.Steps == nil {
+			if ev.P != nil {
+				fmt.Fprintf(r.w, "\t%s\n", ev.P)
+			}
+			fmt.Fprintln(r.w, holds(ev.Outcome))
+		}
+		for i, step := range ev.Steps {
+			fmt.Fprintf(r.w, "step %d: %s\n", i+1, step.Expr)
+			if step.P != nil {
+				fmt.Fprintf(r.w, "\t%s\n", step.P)
+			}
+			fmt.Fprintf(r.w, "\t%s\n", holds(step.Outcome))
+		}
+	}
+	diag.Fprint(r.w, input, in.Diagnostics)
+}
+
+func qed(o parser.Obligation) string {
+	if o.Outcome == parser.Proven {
+		return "qed"
+	}
+	return o.Outcome.String()
+}
+
+func holds(o parser.Outcome) string {
+	switch o {
+	case parser.Proven:
+		return "holds"
+	case parser.Refuted:
+		return "does not hold"
+	default:
+		return o.String()
+	}
+}
@@ -0,0 +1,69 @@
+package repl
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+const prelude = `@func eq(x any, y any) bool;
+@func succ(x any) any;
+@tmpl injectivity(x any, y any) { eq(succ(x), succ(y)) ==> eq(x, y) };`
+
+func TestREPL(t *testing.T) {
+	var out strings.Builder
+	r := New(context.Background(), &out, nil)
+	r.Load("", prelude)
+	input := `term a any;
+term b any;
+eq(succ(a), succ(b)) ==>
+	{ injectivity(a, b) } eq(a, b)
+eq(a, b) ==> { injectivity(a, b) } eq(succ(a), succ(b))
+:type injectivity
+:show injectivity
+:undo
+:type b
+:type a
+:quit
+eq(a, a)
+`
+	if err := r.Run(strings.NewReader(input)); err != nil {
+		t.Fatal(err)
+	}
+	got := out.String()
+	for _, want := range []string{
+		"loaded 2 functions, 0 terms and 1 templates",
+		"step 1: eq(succ(a), succ(b)) ==> eq(a, b) by injectivity(a, b)\n\t",
+		"\tholds\n",
+		"\tdoes not hold\n",
+		"[unsound]",
+		"injectivity tmpl(x any, y any)\n",
+		"@tmpl injectivity(x any, y any) { eq(succ(x), succ(y)) ==> eq(x, y)",
+		"undone\n",
+		"`b' not declared\n",
+		"a any\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("expected %q in output:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "eq(a, a)") {
+		t.Fatalf("input after :quit was executed:\n%s", got)
+	}
+}
+
+func TestComplete(t *testing.T) {
+	for input, want := range map[string]bool{
+		"eq(a, b)":                        true,
+		"eq(a, b) ==>":                    false,
+		"eq(a, (b)":                       false,
+		"term a nat":                      false,
+		"term a nat;":                     true,
+		"tmpl t() { p(1) } {\n\tp(1)":     false,
+		"tmpl t() { p(1) } {\n\tp(1);\n}": false,
+	} {
+		if got := complete(input); got != want {
+			t.Errorf("complete(%q) = %t", input, got)
+		}
+	}
+}
@@ -0,0 +1,395 @@
+// Package sat implements a conflict-driven clause learning (CDCL) solver for
+// propositional formulas in conjunctive normal form.
+//
+// The solver follows the familiar MiniSat design: two watched literals per
+// clause for unit propagation, first-UIP conflict analysis with
+// non-chronological backjumping, VSIDS branching with phase saving, and Luby
+// restarts.
+package sat
+
+// Var is a propositional variable, as returned by Solver.NewVar.
+type Var int
+
+// Lit is a literal, i.e. a Var or its negation.
+type Lit int
+
+// Pos returns the positive literal of v.
+func (v Var) Pos() Lit {
+	return Lit(2 * v)
+}
+
+// Neg returns the negative literal of v.
+func (v Var) Neg() Lit {
+	return Lit(2*v + 1)
+}
+
+// Var returns the variable underlying l.
+func (l Lit) Var() Var {
+	return Var(l >> 1)
+}
+
+// Not returns the negation of l.
+func (l Lit) Not() Lit {
+	return l ^ 1
+}
+
+// IsNeg indicates whether l is a negative literal.
+func (l Lit) IsNeg() bool {
+	return l&1 == 1
+}
+
+type lbool int8
+
+const (
+	lundef lbool = iota
+	ltrue
+	lfalse
+)
+
+type clause struct {
+	// lits[0] and lits[1] are the watched literals. When the clause is the
+	// reason for an assignment lits[0] is the implied literal.
+	lits []Lit
+}
+
+type Solver struct {
+	clauses []*clause
+	learnts []*clause
+
+	// watches[l] holds the clauses watching l, which must be visited when
+	// l becomes false.
+	watches [][]*clause
+
+	assigns  []lbool
+	level    []int
+	reason   []*clause
+	trail    []Lit
+	trailLim []int
+	qhead    int
+
+	activity []float64
+	varInc   float64
+	order    varHeap
+	phase    []bool
+	seen     []bool
+
+	model []bool
+	unsat bool
+
+	// Conflicts is the number of conflicts encountered so far.
+	Conflicts int
+}
+
+const (
+	varDecay     = 0.95
+	restartFirst = 100
+)
+
+func New() *Solver {
+	s := &Solver{varInc: 1}
+	s.order.activity = &s.activity
+	return s
+}
+
+// NewVar creates a fresh variable.
+func (s *Solver) NewVar() Var {
+	v := Var(len(s.assigns))
+	s.watches = append(s.watches, nil, nil)
+	s.assigns = append(s.assigns, lundef)
+	s.level = append(s.level, 0)
+	s.reason = append(s.reason, nil)
+	s.activity = append(s.activity, 0)
+	s.phase = append(s.phase, false)
+	s.seen = append(s.seen, false)
+	s.order.insert(v)
+	return v
+}
+
+// NumVars returns the number of variables created so far.
+func (s *Solver) NumVars() int {
+	return len(s.assigns)
+}
+
+func (s *Solver) value(l Lit) lbool {
+	switch v := s.assigns[l.Var()]; {
+	case v == lundef:
+		return lundef
+	case (v == ltrue) != l.IsNeg():
+		return ltrue
+	default:
+		return lfalse
+	}
+}
+
+func (s *Solver) decisionLevel() int {
+	return len(s.trailLim)
+}
+
+// AddClause adds the disjunction of lits to the formula. It returns false if
+// the formula has thereby become trivially unsatisfiable.
+func (s *Solver) AddClause(lits ...Lit) bool {
+	if s.unsat {
+		return false
+	}
+	s.cancelUntil(0)
+	ps := make([]Lit, 0, len(lits))
+	present := map[Lit]bool{}
+	for _, l := range lits {
+		switch {
+		case present[l.Not()] || s.value(l) == ltrue:
+			// tautologous or already satisfied
+			return true
+		case present[l] || s.value(l) == lfalse:
+			continue
+		}
+		present[l] = true
+		ps = append(ps, l)
+	}
+	switch len(ps) {
+	case 0:
+		s.unsat = true
+		return false
+	case 1:
+		s.enqueue(ps[0], nil)
+		if s.propagate() != nil {
+			s.unsat = true
+			return false
+		}
+		return true
+	}
+	c := &clause{lits: ps}
+	s.attach(c)
+	s.clauses = append(s.clauses, c)
+	return true
+}
+
+func (s *Solver) attach(c *clause) {
+	s.watches[c.lits[0]] = append(s.watches[c.lits[0]], c)
+	s.watches[c.lits[1]] = append(s.watches[c.lits[1]], c)
+}
+
+func (s *Solver) enqueue(l Lit, from *clause) {
+	v := l.Var()
+	if l.IsNeg() {
+		s.assigns[v] = lfalse
+	} else {
+		s.assigns[v] = ltrue
+	}
+	s.level[v] = s.decisionLevel()
+	s.reason[v] = from
+	s.trail = append(s.trail, l)
+}
+
+// propagate performs unit propagation over the trail, returning the
+// conflicting clause if one is found.
+func (s *Solver) propagate() *clause {
+	var confl *clause
+	for s.qhead < len(s.trail) {
+		falseLit := s.trail[s.qhead].Not()
+		s.qhead++
+		ws := s.watches[falseLit]
+		i, j := 0, 0
+		for i < len(ws) {
+			c := ws[i]
+			i++
+			if c.lits[0] == falseLit {
+				c.lits[0], c.lits[1] = c.lits[1], c.lits[0]
+			}
+			if s.value(c.lits[0]) == ltrue {
+				ws[j] = c
+				j++
+				continue
+			}
+			if s.rewatch(c) {
+				continue
+			}
+			ws[j] = c
+			j++
+			if s.value(c.lits[0]) == lfalse {
+				confl = c
+				s.qhead = len(s.trail)
+				for i < len(ws) {
+					ws[j] = ws[i]
+					i++
+					j++
+				}
+			} else {
+				s.enqueue(c.lits[0], c)
+			}
+		}
+		s.watches[falseLit] = ws[:j]
+	}
+	return confl
+}
+
+// rewatch attempts to find a replacement for the false watched literal
+// c.lits[1].
+func (s *Solver) rewatch(c *clause) bool {
+	for k := 2; k < len(c.lits); k++ {
+		if s.value(c.lits[k]) != lfalse {
+			c.lits[1], c.lits[k] = c.lits[k], c.lits[1]
+			s.watches[c.lits[1]] = append(s.watches[c.lits[1]], c)
+			return true
+		}
+	}
+	return false
+}
+
+// analyse derives a first-UIP learnt clause from confl, returning it along
+// with the level to backjump to. The asserting literal is learnt[0].
+func (s *Solver) analyse(confl *clause) ([]Lit, int) {
+	learnt := []Lit{0}
+	pathC := 0
+	p := Lit(-1)
+	idx := len(s.trail) - 1
+	for c := confl; ; {
+		for _, q := range c.lits {
+			if q == p {
+				continue
+			}
+			v := q.Var()
+			if s.seen[v] || s.level[v] == 0 {
+				continue
+			}
+			s.bump(v)
+			s.seen[v] = true
+			if s.level[v] >= s.decisionLevel() {
+				pathC++
+			} else {
+				learnt = append(learnt, q)
+			}
+		}
+		for !s.seen[s.trail[idx].Var()] {
+			idx--
+		}
+		p = s.trail[idx]
+		idx--
+		c = s.reason[p.Var()]
+		s.seen[p.Var()] = false
+		if pathC--; pathC == 0 {
+			break
+		}
+	}
+	learnt[0] = p.Not()
+
+	btlevel := 0
+	for i := 1; i < len(learnt); i++ {
+		s.seen[learnt[i].Var()] = false
+		if lvl := s.level[learnt[i].Var()]; lvl > btlevel {
+			btlevel = lvl
+			learnt[1], learnt[i] = learnt[i], learnt[1]
+		}
+	}
+	return learnt, btlevel
+}
+
+func (s *Solver) bump(v Var) {
+	if s.activity[v] += s.varInc; s.activity[v] > 1e100 {
+		for i := range s.activity {
+			s.activity[i] *= 1e-100
+		}
+		s.varInc *= 1e-100
+	}
+	s.order.update(v)
+}
+
+func (s *Solver) cancelUntil(level int) {
+	if s.decisionLevel() <= level {
+		return
+	}
+	for i := len(s.trail) - 1; i >= s.trailLim[level]; i-- {
+		v := s.trail[i].Var()
+		s.phase[v] = !s.trail[i].IsNeg()
+		s.assigns[v] = lundef
+		s.reason[v] = nil
+		s.order.insert(v)
+	}
+	s.trail = s.trail[:s.trailLim[level]]
+	s.trailLim = s.trailLim[:level]
+	s.qhead = len(s.trail)
+}
+
+func (s *Solver) pickBranch() (Lit, bool) {
+	for !s.order.empty() {
+		v := s.order.pop()
+		if s.assigns[v] != lundef {
+			continue
+		}
+		if s.phase[v] {
+			return v.Pos(), true
+		}
+		return v.Neg(), true
+	}
+	return 0, false
+}
+
+// luby returns the i-th element of the Luby sequence 1, 1, 2, 1, 1, 2, 4, ...
+func luby(i int) int {
+	size, seq := 1, 0
+	for size < i+1 {
+		seq++
+		size = 2*size + 1
+	}
+	for size-1 != i {
+		size = (size - 1) >> 1
+		seq--
+		i = i % size
+	}
+	return 1 << seq
+}
+
+// Solve decides whether the clauses added so far are satisfiable. If they
+// are, the satisfying assignment may be inspected with Value.
+func (s *Solver) Solve() bool {
+	if s.unsat {
+		return false
+	}
+	restarts := 0
+	budget := restartFirst * luby(restarts)
+	for {
+		if confl := s.propagate(); confl != nil {
+			s.Conflicts++
+			if s.decisionLevel() == 0 {
+				s.unsat = true
+				return false
+			}
+			learnt, btlevel := s.analyse(confl)
+			s.cancelUntil(btlevel)
+			if len(learnt) == 1 {
+				s.enqueue(learnt[0], nil)
+			} else {
+				c := &clause{lits: learnt}
+				s.attach(c)
+				s.learnts = append(s.learnts, c)
+				s.enqueue(learnt[0], c)
+			}
+			s.varInc /= varDecay
+			if budget--; budget <= 0 {
+				restarts++
+				budget = restartFirst * luby(restarts)
+				s.cancelUntil(0)
+			}
+			continue
+		}
+		next, ok := s.pickBranch()
+		if !ok {
+			s.model = make([]bool, len(s.assigns))
+			for v := range s.assigns {
+				s.model[v] = s.assigns[v] == ltrue
+			}
+			s.cancelUntil(0)
+			return true
+		}
+		s.trailLim = append(s.trailLim, len(s.trail))
+		s.enqueue(next, nil)
+	}
+}
+
+// Value returns the value of v in the model found by the last successful
+// call to Solve.
+func (s *Solver) Value(v Var) bool {
+	if int(v) >= len(s.model) {
+		return false
+	}
+	return s.model[v]
+}
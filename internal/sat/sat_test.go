@@ -0,0 +1,105 @@
+package sat
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func bruteforce(nvars int, clauses [][]Lit) bool {
+	for m := 0; m < 1<<nvars; m++ {
+		ok := true
+		for _, c := range clauses {
+			sat := false
+			for _, l := range c {
+				if (m>>int(l.Var())&1 == 1) != l.IsNeg() {
+					sat = true
+					break
+				}
+			}
+			if !sat {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+func satisfies(s *Solver, clauses [][]Lit) bool {
+	for _, c := range clauses {
+		sat := false
+		for _, l := range c {
+			if s.Value(l.Var()) != l.IsNeg() {
+				sat = true
+				break
+			}
+		}
+		if !sat {
+			return false
+		}
+	}
+	return true
+}
+
+func TestRandom3SAT(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for n := 0; n < 500; n++ {
+		nvars := 3 + r.Intn(10)
+		nclauses := r.Intn(6 * nvars)
+		s := New()
+		for i := 0; i < nvars; i++ {
+			s.NewVar()
+		}
+		clauses := make([][]Lit, nclauses)
+		for i := range clauses {
+			for j := 0; j < 3; j++ {
+				l := Var(r.Intn(nvars)).Pos()
+				if r.Intn(2) == 0 {
+					l = l.Not()
+				}
+				clauses[i] = append(clauses[i], l)
+			}
+			s.AddClause(clauses[i]...)
+		}
+		want := bruteforce(nvars, clauses)
+		if got := s.Solve(); got != want {
+			t.Fatalf("instance %d: got %t, want %t: %v", n, got, want, clauses)
+		}
+		if want && !satisfies(s, clauses) {
+			t.Fatalf("instance %d: model does not satisfy %v", n, clauses)
+		}
+	}
+}
+
+// TestPigeonhole checks that n+1 pigeons cannot be placed in n holes.
+func TestPigeonhole(t *testing.T) {
+	const holes = 6
+	s := New()
+	p := make([][]Var, holes+1)
+	for i := range p {
+		p[i] = make([]Var, holes)
+		for j := range p[i] {
+			p[i][j] = s.NewVar()
+		}
+	}
+	for i := range p {
+		lits := make([]Lit, holes)
+		for j := range p[i] {
+			lits[j] = p[i][j].Pos()
+		}
+		s.AddClause(lits...)
+	}
+	for j := 0; j < holes; j++ {
+		for i := range p {
+			for k := i + 1; k < len(p); k++ {
+				s.AddClause(p[i][j].Neg(), p[k][j].Neg())
+			}
+		}
+	}
+	if s.Solve() {
+		t.Fatal("pigeonhole instance satisfiable")
+	}
+}
@@ -4,14 +4,30 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"sort"
 	"strings"
 
+	"git.sr.ht/~lbnz/i2/internal/diag"
 	"git.sr.ht/~lbnz/i2/internal/truth"
 )
 
 type AnalysedExpr struct {
 	P   truth.Proposition
 	arg Parameter
+
+	// term is the Term standing for the expression as an argument, or nil
+	// if it has none.
+	term truth.Term
+}
+
+// argTerm returns the Term standing for the expression as an argument. One
+// without a Term, such as a compound Proposition, is opaque and stands for
+// itself by name.
+func (a AnalysedExpr) argTerm() truth.Term {
+	if a.term != nil {
+		return a.term
+	}
+	return truth.Variable(a.arg.Name)
 }
 
 // Expr is the interface describing the AST nodes that are processed into
@@ -20,21 +36,19 @@ type Expr interface {
 	Analyse(Table) (*AnalysedExpr, error)
 	String() string
 
+	// Extent returns the span of source from which the Expr was parsed.
+	Extent() diag.Span
+
 	replace(map[string]Expr) Expr
 }
 
-func paramsToTypes(params []Parameter) []string {
-	arr := make([]string, len(params))
-	for i := range params {
-		arr[i] = string(params[i].Type)
-	}
-	return arr
+type SimpleExpr struct {
+	Name string
+	diag.Span
 }
 
-type SimpleExpr string
-
 func (p SimpleExpr) analyseThis(tbl Table) (*AnalysedExpr, error) {
-	if string(p) != "this" {
+	if p.Name != "this" {
 		// XXX: error
 		return nil, fmt.Errorf("not this")
 	}
@@ -55,13 +69,8 @@ func (p SimpleExpr) analyseThis(tbl Table) (*AnalysedExpr, error) {
 	}
 	return &AnalysedExpr{
 		P: aExpr.P,
-		arg: Parameter{
-			"this", Type(fmt.Sprintf(
-				// must be predicate because from template
-				"func(%s) bool",
-				strings.Join(paramsToTypes(tmpl.Params), ", "),
-			)),
-		},
+		// must be predicate because from template
+		arg: Parameter{"this", tmpl.Type()},
 	}, nil
 }
 
@@ -69,29 +78,40 @@ func (p SimpleExpr) Analyse(tbl Table) (*AnalysedExpr, error) {
 	if expr, err := p.analyseThis(tbl); err == nil {
 		return expr, nil
 	}
-	sym, ok := tbl[string(p)]
+	sym, ok := tbl[p.Name]
 	if !ok {
-		return nil, fmt.Errorf(errVariableNotDefined, p)
+		return nil, diag.Errorf(diag.Analysis, p.Span, errVariableNotDefined, p)
 	}
-	if lp, ok := sym.(LocalProof); ok {
-		return lp.Expr.Analyse(tbl)
-	}
-	typ, ok := sym.(Type)
-	if !ok {
-		return nil, fmt.Errorf(errNonSimpleExpr, string(p))
+	var typ Type
+	switch sym := sym.(type) {
+	case LocalProof:
+		return sym.Expr.Analyse(tbl)
+	case Type:
+		typ = sym
+	case QualifiedTerm:
+		typ = sym.Type
+	case Function:
+		// passed by name, as to a parameter of function type
+		typ = sym.Sig.Type()
+	case Template:
+		typ = sym.Type()
+	default:
+		return nil, diag.Errorf(diag.Analysis, p.Span, errNonSimpleExpr, p)
 	}
+	name := declaredName(sym, p.Name)
 	return &AnalysedExpr{
-		P:   truth.Variable(p),
-		arg: Parameter{string(p), typ},
+		P:    truth.Variable(name),
+		arg:  Parameter{name, typ},
+		term: truth.Variable(name),
 	}, nil
 }
 
 func (p SimpleExpr) String() string {
-	return string(p)
+	return p.Name
 }
 
 func (p SimpleExpr) replace(m map[string]Expr) Expr {
-	if repl, ok := m[string(p)]; ok {
+	if repl, ok := m[p.Name]; ok {
 		return repl
 	}
 	return p
@@ -100,49 +120,85 @@ func (p SimpleExpr) replace(m map[string]Expr) Expr {
 type PostfixExpr struct {
 	Name string
 	Args []Expr
+	diag.Span
 }
 
-func argsToParams(args []Expr, tbl Table) ([]Parameter, error) {
+func argsToParams(args []Expr, tbl Table) ([]Parameter, []truth.Term, error) {
 	params := make([]Parameter, len(args))
+	terms := make([]truth.Term, len(args))
 	for i := range params {
 		expr, err := args[i].Analyse(tbl)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		params[i] = expr.arg
+		terms[i] = expr.argTerm()
 	}
-	return params, nil
-}
-
-func paramsToStrings(params []Parameter) []string {
-	sarr := make([]string, len(params))
-	for i := range params {
-		sarr[i] = params[i].Name
-	}
-	return sarr
+	return params, terms, nil
 }
 
 type invocable interface {
 	IsInvocation([]Parameter) error
 }
 
-func getinvocable(sym Scope, tbl Table) (invocable, error) {
-	if f, ok := sym.(Function); ok {
-		return f, nil
-	} else if t, ok := sym.(Template); ok {
-		return t, nil
+// variable is a parameter of function type, invoked within its scope.
+type variable struct {
+	name string
+	FuncType
+}
+
+func (v variable) IsInvocation(params []Parameter) error {
+	return checkInvocation("parameter", v.name, v.Params, params)
+}
+
+func getinvocable(sym Scope, name string) (invocable, error) {
+	switch sym := sym.(type) {
+	case Function:
+		return sym, nil
+	case Template:
+		return sym, nil
+	case FuncType:
+		return variable{name, sym}, nil
+	case QualifiedTerm:
+		if t, ok := sym.Type.(FuncType); ok {
+			return variable{name, t}, nil
+		}
 	}
 	return nil, errors.New("not invocable")
 }
 
 func invocabletype(sym Scope) Type {
-	if f, ok := sym.(Function); ok {
-		return f.Sig.Return
+	switch sym := sym.(type) {
+	case Function:
+		return sym.Sig.Return
+	case FuncType:
+		return sym.Return
+	case QualifiedTerm:
+		return invocabletype(sym.Type)
 	}
 	// template expressions must be boolean
 	return Bool
 }
 
+// declaredName returns the name with which sym, invoked as name, was
+// declared: it may be invoked by a qualified name if imported. An imported
+// symbol is named apart from those of the importing file.
+func declaredName(sym Scope, name string) string {
+	switch sym := sym.(type) {
+	case QualifiedTerm:
+		return sym.Name
+	case Function:
+		if sym.Name != "" {
+			return sym.Name
+		}
+	case Template:
+		if sym.Name != "" {
+			return sym.Name
+		}
+	}
+	return name
+}
+
 func (p PostfixExpr) analyseThis(tbl Table) (*AnalysedExpr, error) {
 	this, ok := tbl["this"]
 	if !ok {
@@ -156,8 +212,7 @@ func (p PostfixExpr) analyseThis(tbl Table) (*AnalysedExpr, error) {
 	}
 	prop, err := tmpl.instantiate(p.Args, tbl)
 	if err != nil {
-		// XXX: error
-		return nil, fmt.Errorf("instantiate error: %s", err)
+		return nil, err
 	}
 	return &AnalysedExpr{
 		P:   prop,
@@ -168,32 +223,57 @@ func (p PostfixExpr) analyseThis(tbl Table) (*AnalysedExpr, error) {
 func (p PostfixExpr) Analyse(tbl Table) (*AnalysedExpr, error) {
 	if p.Name == "this" {
 		expr, err := p.analyseThis(tbl)
-		if err != nil {
-			return nil, fmt.Errorf("this error: %s", err)
+		var d *diag.Diagnostic
+		if errors.As(err, &d) {
+			// the assertion of `this' does not admit the arguments
+			return nil, err
+		} else if err != nil {
+			return nil, diag.Errorf(
+				diag.Analysis, p.Span, "this error: %s", err,
+			)
 		}
 		return expr, nil
 	}
 	sym, ok := tbl[p.Name]
 	if !ok {
-		return nil, fmt.Errorf(errFunctionOrTemplateNotDefined, p.Name)
+		return nil, diag.Errorf(
+			diag.Analysis, p.Span, errFunctionOrTemplateNotDefined, p.Name,
+		)
 	}
-	inv, err := getinvocable(sym, tbl)
+	inv, err := getinvocable(sym, p.Name)
 	if err != nil {
-		return nil, fmt.Errorf(errNonInvocableInvoked, p.Name)
+		return nil, diag.Errorf(
+			diag.Analysis, p.Span, errNonInvocableInvoked, p.Name,
+		)
 	}
-	params, err := argsToParams(p.Args, tbl)
+	params, terms, err := argsToParams(p.Args, tbl)
 	if err != nil {
 		return nil, err
 	}
 	if err := inv.IsInvocation(params); err != nil {
-		return nil, fmt.Errorf("invocation error: %s", err)
+		s := p.Span
+		var argErr *ArgumentError
+		if errors.As(err, &argErr) && argErr.Position >= 0 {
+			s = p.Args[argErr.Position].Extent()
+		}
+		return nil, diag.Errorf(diag.Analysis, s, "%s", err)
 	}
-	name := fmt.Sprintf(
-		"%s(%s)", p.Name, strings.Join(paramsToStrings(params), ", "),
-	)
+	name := declaredName(sym, p.Name)
+	if f, ok := sym.(Function); ok && f.Body != nil {
+		aExpr, err := f.unfold(p.Args, tbl)
+		if err != nil {
+			return nil, err
+		}
+		aExpr.arg = Parameter{
+			truth.Func(name, terms...).String(), f.Sig.Return,
+		}
+		return aExpr, nil
+	}
+	P := truth.Func(name, terms...)
 	return &AnalysedExpr{
-		P:   truth.Variable(name),
-		arg: Parameter{name, invocabletype(sym)},
+		P:    P,
+		arg:  Parameter{P.String(), invocabletype(sym)},
+		term: truth.Apply(name, terms...),
 	}, nil
 }
 
@@ -211,7 +291,7 @@ func replaceName(name string, m map[string]Expr) string {
 		if !ok {
 			panic(fmt.Sprintf("cannot replace name with %s", repl))
 		}
-		return string(simp)
+		return simp.Name
 	}
 	return name
 }
@@ -221,28 +301,73 @@ func (p PostfixExpr) replace(m map[string]Expr) Expr {
 	for i := range p.Args {
 		args[i] = p.Args[i].replace(m)
 	}
-	return PostfixExpr{Name: replaceName(p.Name, m), Args: args}
+	return PostfixExpr{Name: replaceName(p.Name, m), Args: args, Span: p.Span}
 }
 
-type ConstantExpr bool
+type ConstantExpr struct {
+	Value bool
+	diag.Span
+}
 
 func (c ConstantExpr) Analyse(tbl Table) (*AnalysedExpr, error) {
 	return &AnalysedExpr{
-		P:   truth.Constant(c),
-		arg: Parameter{fmt.Sprintf("%t", c), Bool},
+		P:   truth.Constant(c.Value),
+		arg: Parameter{fmt.Sprintf("%t", c.Value), Bool},
 	}, nil
 }
 
 func (c ConstantExpr) String() string {
-	return fmt.Sprintf("%t", c)
+	return fmt.Sprintf("%t", c.Value)
 }
 
 func (c ConstantExpr) replace(_ map[string]Expr) Expr {
 	return c
 }
 
+// TypeAssertionExpr asserts that Expr is of Type, which must be any or a
+// unary predicate: it analyses as the invocation of the predicate on Expr.
+type TypeAssertionExpr struct {
+	Expr Expr
+	Type Type
+	diag.Span
+}
+
+func (ta TypeAssertionExpr) Analyse(tbl Table) (*AnalysedExpr, error) {
+	if ta.Type == Any {
+		// everything is of type any, but the Expr must still be valid
+		if _, err := ta.Expr.Analyse(tbl); err != nil {
+			return nil, err
+		}
+		return &AnalysedExpr{
+			P: truth.Constant(true), arg: Parameter{ta.String(), Bool},
+		}, nil
+	}
+	f, ok := tbl[ta.Type.String()].(Function)
+	if !ok || !f.isPredicate() || len(f.Sig.Params) != 1 {
+		return nil, diag.Errorf(
+			diag.Analysis, ta.Span, errTypeNotPredicate, ta.Type,
+		)
+	}
+	return PostfixExpr{
+		Name: ta.Type.String(), Args: []Expr{ta.Expr}, Span: ta.Span,
+	}.Analyse(tbl)
+}
+
+func (ta TypeAssertionExpr) String() string {
+	return fmt.Sprintf("%s %s", ta.Expr, ta.Type)
+}
+
+func (ta TypeAssertionExpr) replace(m map[string]Expr) Expr {
+	return TypeAssertionExpr{ta.Expr.replace(m), ta.Type, ta.Span}
+}
+
 type BracketedExpr struct {
 	Expr
+	diag.Span
+}
+
+func (br BracketedExpr) Extent() diag.Span {
+	return br.Span
 }
 
 func (br BracketedExpr) Analyse(tbl Table) (*AnalysedExpr, error) {
@@ -254,11 +379,16 @@ func (br BracketedExpr) String() string {
 }
 
 func (br BracketedExpr) replace(m map[string]Expr) Expr {
-	return BracketedExpr{br.Expr.replace(m)}
+	return BracketedExpr{br.Expr.replace(m), br.Span}
 }
 
 type NegatedExpr struct {
 	Expr
+	diag.Span
+}
+
+func (n NegatedExpr) Extent() diag.Span {
+	return n.Span
 }
 
 func analyseProp(e Expr, tbl Table) (*AnalysedExpr, error) {
@@ -267,8 +397,8 @@ func analyseProp(e Expr, tbl Table) (*AnalysedExpr, error) {
 		return nil, err
 	}
 	if !aExpr.arg.isBool() {
-		return nil, fmt.Errorf(
-			errOpOnNonBoolExpr,
+		return nil, diag.Errorf(
+			diag.Analysis, e.Extent(), errOpOnNonBoolExpr,
 			reflect.TypeOf(e), aExpr.arg.Name, aExpr.arg.Type,
 		)
 	}
@@ -291,7 +421,7 @@ func (n NegatedExpr) String() string {
 }
 
 func (n NegatedExpr) replace(m map[string]Expr) Expr {
-	return NegatedExpr{n.Expr.replace(m)}
+	return NegatedExpr{n.Expr.replace(m), n.Span}
 }
 
 type Operator string
@@ -302,6 +432,8 @@ const (
 	Eqv           = "==="
 	Impl          = "==>"
 	Fllw          = "<=="
+	Eq            = "=="
+	Ne            = "!="
 )
 
 type BinaryOpExpr struct {
@@ -344,6 +476,10 @@ func (b BinaryOpExpr) Analyse(tbl Table) (*AnalysedExpr, error) {
 	}, nil
 }
 
+func (b BinaryOpExpr) Extent() diag.Span {
+	return diag.Join(b.E1.Extent(), b.E2.Extent())
+}
+
 func (b BinaryOpExpr) String() string {
 	return fmt.Sprintf("%s %s %s", b.E1, b.Op, b.E2)
 }
@@ -352,35 +488,173 @@ func (b BinaryOpExpr) replace(m map[string]Expr) Expr {
 	return BinaryOpExpr{b.Op, b.E1.replace(m), b.E2.replace(m)}
 }
 
+// EqualityExpr asserts that E1 and E2 are equal, if Op is Eq, or distinct,
+// if it is Ne. Equality is built in: it is reflexive, symmetric, transitive
+// and preserved by invocation, without citing any template.
+type EqualityExpr struct {
+	Op     Operator
+	E1, E2 Expr
+}
+
+func (eq EqualityExpr) Analyse(tbl Table) (*AnalysedExpr, error) {
+	aExpr1, err := eq.E1.Analyse(tbl)
+	if err != nil {
+		return nil, err
+	}
+	aExpr2, err := eq.E2.Analyse(tbl)
+	if err != nil {
+		return nil, err
+	}
+	t1, t2 := aExpr1.arg.Type, aExpr2.arg.Type
+	if aExpr1.arg.isBool() || aExpr2.arg.isBool() {
+		return nil, diag.Errorf(
+			diag.Analysis, eq.Extent(), errEqualityOnBool, eq.Op,
+		)
+	}
+	if !t1.AssignableTo(t2) && !t2.AssignableTo(t1) {
+		return nil, diag.Errorf(
+			diag.Analysis, eq.Extent(), errEqualityTypeMismatch,
+			eq.E1, t1, eq.E2, t2,
+		)
+	}
+	P := truth.Eq(aExpr1.argTerm(), aExpr2.argTerm())
+	if eq.Op == Ne {
+		P = truth.Not(P)
+	}
+	name := fmt.Sprintf("%s %s %s", aExpr1.arg.Name, eq.Op, aExpr2.arg.Name)
+	return &AnalysedExpr{P: P, arg: Parameter{name, Bool}}, nil
+}
+
+func (eq EqualityExpr) Extent() diag.Span {
+	return diag.Join(eq.E1.Extent(), eq.E2.Extent())
+}
+
+func (eq EqualityExpr) String() string {
+	return fmt.Sprintf("%s %s %s", eq.E1, eq.Op, eq.E2)
+}
+
+func (eq EqualityExpr) replace(m map[string]Expr) Expr {
+	return EqualityExpr{eq.Op, eq.E1.replace(m), eq.E2.replace(m)}
+}
+
 type JustifiableBinaryOpExpr struct {
 	BinaryOpExpr
-	Just *PostfixExpr
+	Just Citations
+}
+
+// Citations are what a step cites in its justification: instances of
+// templates, as PostfixExprs, and the labels of local proofs or hypotheses,
+// as SimpleExprs.
+type Citations []Expr
+
+func (c Citations) String() string {
+	s := make([]string, len(c))
+	for i := range c {
+		s[i] = c[i].String()
+	}
+	return strings.Join(s, ", ")
 }
 
-func (b JustifiableBinaryOpExpr) instantiateJustification(tbl Table) (truth.Proposition, error) {
+// Cites indicates whether c cites the template or proof name.
+func (c Citations) Cites(name string) bool {
+	for _, e := range c {
+		switch e := e.(type) {
+		case PostfixExpr:
+			if e.Name == name {
+				return true
+			}
+		case SimpleExpr:
+			if e.Name == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Justification returns the conjunction of what b cites. The error of a
+// citation that cannot be analysed identifies it, if b cites more than one.
+func (b JustifiableBinaryOpExpr) Justification(tbl Table) (truth.Proposition, error) {
 	if b.Just == nil {
 		return nil, fmt.Errorf("cannot justify with nil")
 	}
-	if _, err := b.Just.Analyse(tbl); err != nil {
-		// TODO: error
+	var just truth.Proposition
+	for i, e := range b.Just {
+		p, err := cite(e, tbl)
+		if err != nil {
+			if len(b.Just) > 1 {
+				err = fmt.Errorf(
+					"justification %d `%s': %w", i+1, e, err,
+				)
+			}
+			return nil, err
+		}
+		if just == nil {
+			just = p
+		} else {
+			just = truth.And(just, p)
+		}
+	}
+	return just, nil
+}
+
+// cite returns the Proposition that e cites: the instantiation of a template
+// or the burden of a local proof.
+func cite(e Expr, tbl Table) (truth.Proposition, error) {
+	switch e := e.(type) {
+	case PostfixExpr:
+		return instantiateJustification(e, tbl)
+	case SimpleExpr:
+		switch sym := tbl[e.Name].(type) {
+		case LocalProof:
+			aExpr, err := sym.Expr.Analyse(tbl)
+			if err != nil {
+				return nil, err
+			}
+			return aExpr.P, nil
+		case Template:
+			// a hypothesis, cited by name
+			return instantiateJustification(
+				PostfixExpr{Name: e.Name, Args: []Expr{}, Span: e.Span},
+				tbl,
+			)
+		case nil:
+			return nil, diag.Errorf(
+				diag.Analysis, e.Span, errVariableNotDefined, e,
+			)
+		}
+		return nil, diag.Errorf(diag.Analysis, e.Span, errNotCitable, e)
+	default:
+		return nil, diag.Errorf(diag.Analysis, e.Extent(), errNotCitable, e)
+	}
+}
+
+// instantiateJustification returns the instantiation of the template cited
+// by just.
+func instantiateJustification(just PostfixExpr, tbl Table) (truth.Proposition, error) {
+	if _, err := just.Analyse(tbl); err != nil {
 		return nil, err
 	}
-	sym, ok := tbl[b.Just.Name]
+	sym, ok := tbl[just.Name]
 	if !ok {
-		// TODO: error
-		return nil, fmt.Errorf(errFunctionOrTemplateNotDefined, b.Just.Name)
+		return nil, diag.Errorf(
+			diag.Analysis, just.Span,
+			errFunctionOrTemplateNotDefined, just.Name,
+		)
 	}
 	tmpl, ok := sym.(Template)
 	if !ok {
-		// TODO: nonTemplateInvoked
-		return nil, fmt.Errorf(errNonInvocableInvoked, b.Just.Name)
+		return nil, diag.Errorf(
+			diag.Analysis, just.Span,
+			errNonInvocableInvoked, just.Name,
+		)
 	}
-	just, err := tmpl.instantiate(b.Just.Args, tbl)
+	p, err := tmpl.instantiate(just.Args, tbl)
 	if err != nil {
 		// TODO: error
 		return nil, err
 	}
-	return just, nil
+	return p, nil
 }
 
 func justify(just, P, Q truth.Proposition, op Operator) truth.Proposition {
@@ -408,7 +682,7 @@ func (b JustifiableBinaryOpExpr) Analyse(tbl Table) (*AnalysedExpr, error) {
 	if err != nil {
 		return nil, err
 	}
-	just, err := b.instantiateJustification(tbl)
+	just, err := b.Justification(tbl)
 	if err != nil {
 		// TODO error
 		return nil, err
@@ -424,7 +698,203 @@ func (b JustifiableBinaryOpExpr) String() string {
 	if b.Just == nil {
 		return fmt.Sprintf("%s %s %s [UJ]", b.E1, b.Op, b.E2)
 	}
-	return fmt.Sprintf("%s %s %s by %s", b.E1, b.Op, b.E2, *b.Just)
+	return fmt.Sprintf("%s %s %s by %s", b.E1, b.Op, b.E2, b.Just)
+}
+
+// Source returns e in the syntax of i2, in which, unlike its String, steps
+// are written with their justifications between the connective and the
+// following expression.
+func Source(e Expr) string {
+	switch e := e.(type) {
+	case PostfixExpr:
+		sarr := make([]string, len(e.Args))
+		for i, arg := range e.Args {
+			sarr[i] = Source(arg)
+		}
+		return fmt.Sprintf("%s(%s)", e.Name, strings.Join(sarr, ", "))
+	case TypeAssertionExpr:
+		return fmt.Sprintf("%s %s", Source(e.Expr), e.Type)
+	case BracketedExpr:
+		return fmt.Sprintf("(%s)", Source(e.Expr))
+	case NegatedExpr:
+		return fmt.Sprintf("!%s", Source(e.Expr))
+	case BinaryOpExpr:
+		return fmt.Sprintf("%s %s %s", Source(e.E1), e.Op, Source(e.E2))
+	case EqualityExpr:
+		return fmt.Sprintf("%s %s %s", Source(e.E1), e.Op, Source(e.E2))
+	case JustifiableBinaryOpExpr:
+		if e.Just == nil {
+			return Source(e.BinaryOpExpr)
+		}
+		just := make([]string, len(e.Just))
+		for i := range e.Just {
+			just[i] = Source(e.Just[i])
+		}
+		return fmt.Sprintf("%s %s { %s } %s", Source(e.E1), e.Op,
+			strings.Join(just, ", "), Source(e.E2))
+	case LambdaExpr:
+		return fmt.Sprintf("(%s) { %s }", strings.Join(
+			paramsToTypeAssertionList(e.Params), ", "), Source(e.Expr))
+	case ExistentialExpr:
+		return fmt.Sprintf("[%s] { %s }", strings.Join(
+			paramsToTypeAssertionList(e.Params), ", "), Source(e.Expr))
+	default:
+		return e.String()
+	}
+}
+
+// Atoms maps the atoms of the Proposition e analyses to back onto the
+// sub-expressions of e that they arise from.
+func Atoms(e Expr, tbl Table) map[truth.Variable]Expr {
+	m := map[truth.Variable]Expr{}
+	collectAtoms(e, tbl, m)
+	return m
+}
+
+func collectAtoms(e Expr, tbl Table, m map[truth.Variable]Expr) {
+	switch e := e.(type) {
+	case JustifiableBinaryOpExpr:
+		collectAtoms(e.BinaryOpExpr, tbl, m)
+	case BinaryOpExpr:
+		collectAtoms(e.E1, tbl, m)
+		collectAtoms(e.E2, tbl, m)
+	case NegatedExpr:
+		collectAtoms(e.Expr, tbl, m)
+	case EqualityExpr:
+		// the atom is that of the equality, even if e denies it
+		e.Op = Eq
+		aExpr, err := e.Analyse(tbl)
+		if err != nil {
+			return
+		}
+		if v, ok := truth.Atom(aExpr.P); ok {
+			m[v] = e
+		}
+	case BracketedExpr:
+		collectAtoms(e.Expr, tbl, m)
+	default:
+		aExpr, err := e.Analyse(tbl)
+		if err != nil {
+			return
+		}
+		if v, ok := truth.Atom(aExpr.P); ok {
+			m[v] = e
+		}
+	}
+}
+
+// invokes reports whether e refers to name, directly or through the bodies
+// of the functions in tbl that it invokes. Those in visited are not searched.
+func invokes(e Expr, name string, tbl Table, visited map[string]bool) bool {
+	switch e := e.(type) {
+	case SimpleExpr:
+		return e.Name == name
+	case PostfixExpr:
+		if e.Name == name {
+			return true
+		}
+		for _, arg := range e.Args {
+			if invokes(arg, name, tbl, visited) {
+				return true
+			}
+		}
+		f, ok := tbl[e.Name].(Function)
+		if !ok || f.Body == nil || visited[e.Name] {
+			return false
+		}
+		visited[e.Name] = true
+		return invokes(f.Body, name, tbl, visited)
+	case JustifiableBinaryOpExpr:
+		return invokes(e.BinaryOpExpr, name, tbl, visited)
+	case BinaryOpExpr:
+		return invokes(e.E1, name, tbl, visited) ||
+			invokes(e.E2, name, tbl, visited)
+	case NegatedExpr:
+		return invokes(e.Expr, name, tbl, visited)
+	case EqualityExpr:
+		return invokes(e.E1, name, tbl, visited) ||
+			invokes(e.E2, name, tbl, visited)
+	case TypeAssertionExpr:
+		return invokes(PostfixExpr{
+			Name: e.Type.String(), Args: []Expr{e.Expr},
+		}, name, tbl, visited)
+	case BracketedExpr:
+		return invokes(e.Expr, name, tbl, visited)
+	case LambdaExpr:
+		return invokes(e.Expr, name, tbl, visited)
+	case ExistentialExpr:
+		return invokes(e.Expr, name, tbl, visited)
+	default:
+		return false
+	}
+}
+
+// Mentions indicates whether e refers to the symbol name, leaving aside the
+// justifications of its relations.
+func Mentions(e Expr, name string) bool {
+	return invokes(e, name, Table{}, map[string]bool{})
+}
+
+// Bound returns the parameters bound by the quantified sub-expressions of e,
+// of the bodies of the functions in tbl that it invokes, and of what its
+// relations cite.
+func Bound(e Expr, tbl Table) []Parameter {
+	var params []Parameter
+	visited := map[string]bool{}
+	var walk func(Expr)
+	follow := func(name string) {
+		if visited[name] {
+			return
+		}
+		visited[name] = true
+		switch sym := tbl[name].(type) {
+		case Function:
+			if sym.Body != nil {
+				walk(sym.Body)
+			}
+		case Template:
+			walk(sym.E)
+		case LocalProof:
+			walk(sym.Expr)
+		}
+	}
+	walk = func(e Expr) {
+		switch e := e.(type) {
+		case PostfixExpr:
+			follow(e.Name)
+			for _, arg := range e.Args {
+				walk(arg)
+			}
+		case JustifiableBinaryOpExpr:
+			walk(e.BinaryOpExpr)
+			for _, c := range e.Just {
+				walk(c)
+				if s, ok := c.(SimpleExpr); ok {
+					follow(s.Name)
+				}
+			}
+		case BinaryOpExpr:
+			walk(e.E1)
+			walk(e.E2)
+		case EqualityExpr:
+			walk(e.E1)
+			walk(e.E2)
+		case NegatedExpr:
+			walk(e.Expr)
+		case BracketedExpr:
+			walk(e.Expr)
+		case TypeAssertionExpr:
+			walk(e.Expr)
+		case LambdaExpr:
+			params = append(params, e.Params...)
+			walk(e.Expr)
+		case ExistentialExpr:
+			params = append(params, e.Params...)
+			walk(e.Expr)
+		}
+	}
+	walk(e)
+	return params
 }
 
 // quantiseWithSides is a utility function used by Quantise to break an Expr
@@ -506,10 +976,18 @@ func (rel RelationChain) Label() string {
 	return ""
 }
 
+func (rel RelationChain) Extent() diag.Span {
+	if len(rel) == 0 {
+		return diag.Span{}
+	}
+	return diag.Join(rel[0].Extent(), rel[len(rel)-1].Extent())
+}
+
 type Proof interface {
 	Chain() RelationChain
 	Burden() (Expr, error)
 	Label() string
+	Extent() diag.Span
 }
 
 type LabelledChain struct {
@@ -524,39 +1002,118 @@ func (c LabelledChain) Label() string {
 type ProofChain struct {
 	Preamble []Proof
 	Proof    Proof
+
+	// Form is the structure of the proof if it is not a single chain, in
+	// which case Proof is nil.
+	Form Form
+	diag.Span
 }
 
+// LambdaExpr is a universally quantified expression: it holds if Expr does
+// for every value of Params.
 type LambdaExpr struct {
 	Params []Parameter
 	Expr   Expr
+	diag.Span
 }
 
-func (λ LambdaExpr) Table() (Table, error) {
+func paramsTable(params []Parameter) Table {
 	T := Table{}
-	for _, p := range λ.Params {
+	for _, p := range params {
 		T[p.Name] = p.Type
 	}
-	return T, nil
+	return T
 }
 
-func (λ LambdaExpr) Analyse(tbl Table) (*AnalysedExpr, error) {
-	λtbl, err := λ.Table()
+func (λ LambdaExpr) Table() (Table, error) {
+	return paramsTable(λ.Params), nil
+}
+
+// quantify returns the Proposition formed by binding each of params in the
+// analysis of e with the quantifier q, outermost first. The scope of each
+// parameter of a type other than `any' is restricted to the values of its
+// type by connecting the assertion of its type to the scope with guard.
+func quantify(q func(truth.Variable, truth.Proposition) truth.Proposition,
+	guard BinaryOp, params []Parameter, e Expr, tbl Table) (*AnalysedExpr, error) {
+	scope := paramsTable(params).Nest(tbl)
+	aExpr, err := e.Analyse(scope)
 	if err != nil {
-		return nil, fmt.Errorf("table error: %s", err)
+		return nil, fmt.Errorf("expr analysis error: %w", err)
+	}
+	P := aExpr.P
+	for i := len(params) - 1; i >= 0; i-- {
+		typing, err := typeGuard(params[i], scope)
+		if err != nil {
+			return nil, err
+		}
+		if typing != nil {
+			P = guard(typing, P)
+		}
+		P = q(truth.Variable(params[i].Name), P)
+	}
+	return &AnalysedExpr{P: P, arg: Parameter{P.String(), Bool}}, nil
+}
+
+// typeGuard returns the Proposition that the parameter p, bound in tbl, is
+// of its type, or nil if every value is: those of type `any' and bool, and
+// of function types, are unrestricted. A type without a predicate declared
+// is asserted by an undefined predicate of its name.
+func typeGuard(p Parameter, tbl Table) (truth.Proposition, error) {
+	if p.Type == Any || p.Type == Bool {
+		return nil, nil
+	}
+	if _, ok := p.Type.(FuncType); ok {
+		return nil, nil
+	}
+	if _, ok := tbl[p.Type.String()]; !ok {
+		return truth.Func(p.Type.String(), truth.Variable(p.Name)), nil
 	}
-	aExpr, err := λ.Expr.Analyse(λtbl.Nest(tbl))
+	aExpr, err := TypeAssertionExpr{
+		Expr: SimpleExpr{Name: p.Name}, Type: p.Type,
+	}.Analyse(tbl)
 	if err != nil {
-		return nil, fmt.Errorf("expr analysis error: %s", err)
+		return nil, err
 	}
-	pseudolambda := fmt.Sprintf(
-		"ψ(%s) { %s }",
-		strings.Join(paramsToTypeAssertionList(λ.Params), ", "),
-		aExpr.P.String(),
-	)
-	return &AnalysedExpr{
-		P:   truth.Variable(pseudolambda),
-		arg: Parameter{pseudolambda, Bool},
-	}, nil
+	return aExpr.P, nil
+}
+
+// Typing returns the conjunction of the assertions that the variables and
+// constants of tbl are of their types, which hold throughout its scope, or
+// nil if there are none. A type that cannot be asserted is left out, for
+// its assertions are reported where they are written.
+func Typing(tbl Table) truth.Proposition {
+	types := map[string]Type{}
+	for name, sym := range tbl {
+		switch sym := sym.(type) {
+		case Type:
+			types[name] = sym
+		case QualifiedTerm:
+			// an imported term may be bound both qualified and not
+			types[sym.Name] = sym.Type
+		}
+	}
+	names := make([]string, 0, len(types))
+	for name := range types {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var typing truth.Proposition
+	for _, name := range names {
+		P, err := typeGuard(Parameter{name, types[name]}, tbl)
+		if err != nil || P == nil {
+			continue
+		}
+		if typing == nil {
+			typing = P
+		} else {
+			typing = truth.And(typing, P)
+		}
+	}
+	return typing
+}
+
+func (λ LambdaExpr) Analyse(tbl Table) (*AnalysedExpr, error) {
+	return quantify(truth.Universal, truth.Impl, λ.Params, λ.Expr, tbl)
 }
 
 func paramsToTypeAssertionList(params []Parameter) []string {
@@ -575,15 +1132,148 @@ func (λ LambdaExpr) String() string {
 	)
 }
 
-func (λ LambdaExpr) replace(m map[string]Expr) Expr {
-	for _, p := range λ.Params {
-		for k := range m {
-			if p.Name == k {
-				panic("bound replace NOT IMPLEMENTED")
-			}
+// unbound returns m without the replacements of names bound by params, which
+// are shadowed within their scope.
+func unbound(params []Parameter, m map[string]Expr) map[string]Expr {
+	free := map[string]Expr{}
+	for k, v := range m {
+		free[k] = v
+	}
+	for _, p := range params {
+		delete(free, p.Name)
+	}
+	return free
+}
+
+// rebind returns params and their scope e renamed apart from the names
+// occurring free in the replacements of m that apply within it, so that
+// substituting them captures none of those names, along with those
+// replacements.
+func rebind(params []Parameter, e Expr, m map[string]Expr) ([]Parameter,
+	Expr, map[string]Expr) {
+	m = unbound(params, m)
+	inner := map[string]bool{}
+	freeNames(e, map[string]bool{}, inner)
+	captured := map[string]bool{}
+	for name, repl := range m {
+		if inner[name] {
+			freeNames(repl, map[string]bool{}, captured)
+		}
+	}
+	avoid := map[string]bool{}
+	for name := range captured {
+		avoid[name] = true
+	}
+	for name := range inner {
+		avoid[name] = true
+	}
+	for _, p := range params {
+		avoid[p.Name] = true
+	}
+	var renames map[string]Expr
+	renamed := make([]Parameter, len(params))
+	for i, p := range params {
+		renamed[i] = p
+		if !captured[p.Name] {
+			continue
+		}
+		name := p.Name
+		for j := 0; avoid[name]; j++ {
+			name = fmt.Sprintf("%s%d", p.Name, j)
+		}
+		avoid[name] = true
+		if renames == nil {
+			renames = map[string]Expr{}
 		}
+		renames[p.Name] = SimpleExpr{Name: name}
+		renamed[i].Name = name
 	}
-	return LambdaExpr{λ.Params, λ.Expr.replace(m)}
+	if renames != nil {
+		e = e.replace(renames)
+	}
+	return renamed, e, m
+}
+
+// freeNames adds to names those that occur in e other than those of bound
+// and those bound within e.
+func freeNames(e Expr, bound, names map[string]bool) {
+	add := func(name string) {
+		if !bound[name] {
+			names[name] = true
+		}
+	}
+	within := func(params []Parameter, e Expr) {
+		scope := map[string]bool{}
+		for name := range bound {
+			scope[name] = true
+		}
+		for _, p := range params {
+			scope[p.Name] = true
+		}
+		freeNames(e, scope, names)
+	}
+	switch e := e.(type) {
+	case SimpleExpr:
+		add(e.Name)
+	case PostfixExpr:
+		add(e.Name)
+		for _, arg := range e.Args {
+			freeNames(arg, bound, names)
+		}
+	case TypeAssertionExpr:
+		freeNames(e.Expr, bound, names)
+	case BracketedExpr:
+		freeNames(e.Expr, bound, names)
+	case NegatedExpr:
+		freeNames(e.Expr, bound, names)
+	case BinaryOpExpr:
+		freeNames(e.E1, bound, names)
+		freeNames(e.E2, bound, names)
+	case JustifiableBinaryOpExpr:
+		freeNames(e.E1, bound, names)
+		freeNames(e.E2, bound, names)
+	case EqualityExpr:
+		freeNames(e.E1, bound, names)
+		freeNames(e.E2, bound, names)
+	case LambdaExpr:
+		within(e.Params, e.Expr)
+	case ExistentialExpr:
+		within(e.Params, e.Expr)
+	}
+}
+
+func (λ LambdaExpr) replace(m map[string]Expr) Expr {
+	params, e, m := rebind(λ.Params, λ.Expr, m)
+	return LambdaExpr{params, e.replace(m), λ.Span}
+}
+
+// ExistentialExpr is an existentially quantified expression: it holds if
+// Expr does for some value of Params.
+type ExistentialExpr struct {
+	Params []Parameter
+	Expr   Expr
+	diag.Span
+}
+
+func (ex ExistentialExpr) Table() (Table, error) {
+	return paramsTable(ex.Params), nil
+}
+
+func (ex ExistentialExpr) Analyse(tbl Table) (*AnalysedExpr, error) {
+	return quantify(truth.Existential, truth.And, ex.Params, ex.Expr, tbl)
+}
+
+func (ex ExistentialExpr) String() string {
+	return fmt.Sprintf(
+		"[%s] { %s }",
+		strings.Join(paramsToTypeAssertionList(ex.Params), ", "),
+		ex.Expr.String(),
+	)
+}
+
+func (ex ExistentialExpr) replace(m map[string]Expr) Expr {
+	params, e, m := rebind(ex.Params, ex.Expr, m)
+	return ExistentialExpr{params, e.replace(m), ex.Span}
 }
 
 type LambdaProof struct {
@@ -604,9 +1294,13 @@ func (prf LambdaProof) Burden() (Expr, error) {
 	if err != nil {
 		return nil, err
 	}
-	return LambdaExpr{prf.E.Params, relburden}, nil
+	return LambdaExpr{prf.E.Params, relburden, prf.E.Span}, nil
 }
 
 func (prf LambdaProof) Label() string {
 	return prf.L
 }
+
+func (prf LambdaProof) Extent() diag.Span {
+	return prf.E.Span
+}
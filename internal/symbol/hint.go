@@ -0,0 +1,142 @@
+package symbol
+
+import "sort"
+
+// Terms returns the distinct sub-expressions of e that may be given as the
+// arguments of a citation: its terms and the names of the functions and
+// predicates it applies.
+func Terms(e Expr) []Expr {
+	seen := map[string]bool{}
+	var terms []Expr
+	add := func(t Expr) {
+		if !seen[t.String()] {
+			seen[t.String()] = true
+			terms = append(terms, t)
+		}
+	}
+	var walk func(Expr)
+	walk = func(e Expr) {
+		switch e := e.(type) {
+		case SimpleExpr:
+			add(e)
+		case PostfixExpr:
+			add(SimpleExpr{Name: e.Name, Span: e.Span})
+			add(e)
+			for _, arg := range e.Args {
+				walk(arg)
+			}
+		case JustifiableBinaryOpExpr:
+			walk(e.BinaryOpExpr)
+		case BinaryOpExpr:
+			walk(e.E1)
+			walk(e.E2)
+		case EqualityExpr:
+			walk(e.E1)
+			walk(e.E2)
+		case NegatedExpr:
+			walk(e.Expr)
+		case BracketedExpr:
+			walk(e.Expr)
+		case TypeAssertionExpr:
+			walk(e.Expr)
+		}
+	}
+	walk(e)
+	return terms
+}
+
+// Size is the number of symbols in e, by which simpler citations are
+// preferred.
+func Size(e Expr) int {
+	switch e := e.(type) {
+	case PostfixExpr:
+		n := 1
+		for _, arg := range e.Args {
+			n += Size(arg)
+		}
+		return n
+	default:
+		return 1
+	}
+}
+
+// Instances returns the citations of the template tmpl, bound to name, with
+// arguments among terms, each of a type its parameter accepts. At most limit
+// are returned, the smallest first.
+func Instances(name string, tmpl Template, terms []Expr, tbl Table, limit int) []PostfixExpr {
+	typed := make([]Parameter, 0, len(terms))
+	exprs := make([]Expr, 0, len(terms))
+	for _, t := range terms {
+		aExpr, err := t.Analyse(tbl)
+		if err != nil || aExpr.arg.Type == nil {
+			continue
+		}
+		typed = append(typed, aExpr.arg)
+		exprs = append(exprs, t)
+	}
+	// the arguments each parameter accepts; terms of type bool, which are
+	// propositions, are given only to parameters of type bool
+	choices := make([][]int, len(tmpl.Params))
+	for i, p := range tmpl.Params {
+		for j, arg := range typed {
+			if (arg.Type == Bool) != (p.Type == Bool) {
+				continue
+			}
+			if arg.Type.AssignableTo(p.Type) {
+				choices[i] = append(choices[i], j)
+			}
+		}
+	}
+	var instances []PostfixExpr
+	args := make([]Expr, len(tmpl.Params))
+	params := make([]Parameter, len(tmpl.Params))
+	var enumerate func(int) bool
+	enumerate = func(i int) bool {
+		if len(instances) >= limit {
+			return false
+		}
+		if i == len(tmpl.Params) {
+			if tmpl.IsInvocation(params) == nil {
+				instances = append(instances, PostfixExpr{
+					Name: name, Args: append([]Expr{}, args...),
+				})
+			}
+			return true
+		}
+		for _, j := range choices[i] {
+			args[i], params[i] = exprs[j], typed[j]
+			if !enumerate(i + 1) {
+				return false
+			}
+		}
+		return true
+	}
+	enumerate(0)
+	sort.SliceStable(instances, func(i, j int) bool {
+		return Size(instances[i]) < Size(instances[j])
+	})
+	return instances
+}
+
+// Rewrites returns the expressions to which a step by op may lead from what
+// the assertion s of a citation relates: the consequent of an implication for
+// `==>', its antecedent for `<==', and either side of an equivalence.
+func Rewrites(s Expr, op Operator) []Expr {
+	s = unbracket(s)
+	if j, ok := s.(JustifiableBinaryOpExpr); ok {
+		s = j.BinaryOpExpr
+	}
+	b, ok := s.(BinaryOpExpr)
+	if !ok {
+		return nil
+	}
+	switch {
+	case b.Op == Eqv:
+		return []Expr{b.E1, b.E2}
+	case b.Op == Impl && op == Impl, b.Op == Fllw && op == Fllw:
+		return []Expr{b.E2}
+	case b.Op == Impl && op == Fllw, b.Op == Fllw && op == Impl:
+		return []Expr{b.E1}
+	}
+	return nil
+}
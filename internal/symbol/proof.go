@@ -0,0 +1,299 @@
+package symbol
+
+import (
+	"fmt"
+
+	"git.sr.ht/~lbnz/i2/internal/diag"
+)
+
+// Form is the structure of a proof that divides the assertion of a template
+// into obligations, each proven by a ProofChain of its own.
+type Form interface {
+	Extent() diag.Span
+}
+
+// Induction is a proof by induction on the parameter Var of a template, using
+// the template Axiom: Base and Step prove the obligations that the antecedent
+// of the axiom places on the assertion.
+type Induction struct {
+	Var   string
+	Axiom string
+	Base  ProofChain
+	Step  ProofChain
+	diag.Span
+
+	// Using is the span of the clause citing the Axiom.
+	Using diag.Span
+}
+
+func (ind Induction) Extent() diag.Span {
+	return ind.Span
+}
+
+// InductionObligations are the obligations of a proof by induction.
+type InductionObligations struct {
+	// This is the assertion of the template as a predicate of the
+	// variable of induction, in the scope of which the obligations are
+	// stated.
+	This Template
+
+	// Base and Step are the obligations proven by the base case and the
+	// inductive step. The variable bound by the step, if any, is renamed
+	// to that of induction so that it may be proven for an arbitrary one.
+	Base, Step Expr
+
+	// Conclusion is what the axiom concludes from them, which must entail
+	// the assertion.
+	Conclusion Expr
+}
+
+// Obligations instantiates the axiom of ind with the assertion of tmpl. The
+// axiom must be a template of a single predicate P whose assertion is of the
+// form `base && step ==> conclusion'.
+func (ind Induction) Obligations(tmpl Template, tbl Table) (*InductionObligations, error) {
+	var typ Type
+	found := false
+	for _, p := range tmpl.Params {
+		if p.Name == ind.Var {
+			typ, found = p.Type, true
+		}
+	}
+	if !found {
+		return nil, diag.Errorf(
+			diag.Proof, ind.Span, errNotParameter, ind.Var, tmpl.Name,
+		)
+	}
+	axiom, ok := tbl[ind.Axiom].(Template)
+	if !ok || len(axiom.Params) != 1 {
+		return nil, diag.Errorf(
+			diag.Proof, ind.Span, errNotInductionAxiom, ind.Axiom,
+		)
+	}
+	pred := FunctionSignature{[]Parameter{{ind.Var, typ}}, Bool}.Type()
+	if !pred.AssignableTo(axiom.Params[0].Type) {
+		return nil, diag.Errorf(
+			diag.Proof, ind.Span, errInductionType,
+			ind.Axiom, axiom.Params[0].Type, ind.Var, typ,
+		)
+	}
+	stmt := axiom.E.replace(map[string]Expr{
+		axiom.Params[0].Name: SimpleExpr{Name: "this"},
+	})
+	antecedent, conclusion, ok := implication(stmt)
+	if !ok {
+		return nil, diag.Errorf(
+			diag.Proof, ind.Span, errNotInductionAxiom, ind.Axiom,
+		)
+	}
+	conjuncts := conjunction(antecedent)
+	if len(conjuncts) != 2 {
+		return nil, diag.Errorf(
+			diag.Proof, ind.Span, errNotInductionAxiom, ind.Axiom,
+		)
+	}
+	step := conjuncts[1]
+	if λ, ok := step.(LambdaExpr); ok && len(λ.Params) == 1 {
+		step = λ.Expr.replace(map[string]Expr{
+			λ.Params[0].Name: SimpleExpr{Name: ind.Var},
+		})
+	}
+	obl := &InductionObligations{
+		This: Template{
+			Params: []Parameter{{ind.Var, typ}},
+			E:      tmpl.E,
+			Name:   tmpl.Name,
+			Span:   tmpl.Span,
+		},
+		Base:       conjuncts[0],
+		Step:       step,
+		Conclusion: conclusion,
+	}
+	// the axiom may invoke its predicate with arguments that the assertion
+	// does not admit
+	scope, err := tmpl.Table()
+	if err != nil {
+		return nil, err
+	}
+	scope["this"] = obl.This
+	scope = scope.Nest(tbl)
+	for _, e := range []Expr{obl.Base, obl.Step, obl.Conclusion} {
+		if _, err := e.Analyse(scope); err != nil {
+			return nil, diag.Errorf(
+				diag.Proof, ind.Using, errInductionInstance,
+				ind.Axiom, tmpl.Name, err,
+			)
+		}
+	}
+	return obl, nil
+}
+
+// Cases is a proof by case analysis: the conditions of the Cases must be
+// exhaustive, and each must prove the assertion given its condition.
+type Cases struct {
+	Cases []Case
+	diag.Span
+}
+
+func (c Cases) Extent() diag.Span {
+	return c.Span
+}
+
+// Case is a single case of a proof by case analysis.
+type Case struct {
+	Cond  Expr
+	Proof ProofChain
+	diag.Span
+}
+
+// Goal returns the obligation of the case c in proving assertion.
+func (c Case) Goal(assertion Expr) Expr {
+	return BinaryOpExpr{
+		Op: Impl,
+		E1: BracketedExpr{Expr: c.Cond, Span: c.Cond.Extent()},
+		E2: BracketedExpr{Expr: assertion, Span: assertion.Extent()},
+	}
+}
+
+// Exhaustion returns the disjunction of the conditions of the cases, which
+// must hold for them to be exhaustive in proving assertion. If assertion is
+// an implication, the disjunction need hold only given its antecedent.
+func (c Cases) Exhaustion(assertion Expr) Expr {
+	var e Expr
+	for _, cs := range c.Cases {
+		cond := BracketedExpr{Expr: cs.Cond, Span: cs.Cond.Extent()}
+		if e == nil {
+			e = cond
+		} else {
+			e = BinaryOpExpr{Op: Or, E1: e, E2: cond}
+		}
+	}
+	if antecedent, _, ok := implication(assertion); ok && e != nil {
+		e = BinaryOpExpr{
+			Op: Impl,
+			E1: BracketedExpr{Expr: antecedent, Span: antecedent.Extent()},
+			E2: BracketedExpr{Expr: e, Span: c.Span},
+		}
+	}
+	return e
+}
+
+// Hypothesis is the name under which a proof may cite what it assumes, as
+// the template of no parameters asserting it.
+const Hypothesis = "hypothesis"
+
+// Contradiction is a proof by contradiction: it assumes the negation of the
+// assertion and derives `false' from it.
+type Contradiction struct {
+	Proof ProofChain
+	diag.Span
+}
+
+func (c Contradiction) Extent() diag.Span {
+	return c.Span
+}
+
+// Hypothesis returns the template of the assumption that the assertion of
+// tmpl is false.
+func (c Contradiction) Hypothesis(tmpl Template) Template {
+	return Template{
+		Params: []Parameter{},
+		E: NegatedExpr{
+			Expr: BracketedExpr{Expr: tmpl.E, Span: tmpl.E.Extent()},
+			Span: tmpl.E.Extent(),
+		},
+		Name: Hypothesis,
+		Span: c.Span,
+	}
+}
+
+// Refutes checks that the proof of c ends in `false'.
+func (c Contradiction) Refutes() error {
+	chain := c.Proof.Proof.Chain()
+	last := chain[len(chain)-1].E2
+	if k, ok := unbracket(last).(ConstantExpr); ok && !k.Value {
+		return nil
+	}
+	return diag.Errorf(diag.Proof, last.Extent(), errNotContradiction, last)
+}
+
+// Assumption is a proof of an implication that assumes the expression
+// Assumed, citable as the template of no parameters named Label, and proves
+// the consequent from it.
+type Assumption struct {
+	Label   string
+	Assumed Expr
+	Proof   ProofChain
+	diag.Span
+}
+
+func (a Assumption) Extent() diag.Span {
+	return a.Span
+}
+
+// Hypothesis returns the template of the assumption.
+func (a Assumption) Hypothesis() Template {
+	return Template{
+		Params: []Parameter{},
+		E:      a.Assumed,
+		Name:   a.Label,
+		Span:   a.Span,
+	}
+}
+
+// Obligations returns the antecedent of the assertion of tmpl, which must
+// entail the assumption, and the goal of the proof of a, that the assumption
+// implies the consequent.
+func (a Assumption) Obligations(tmpl Template) (antecedent, goal Expr, err error) {
+	antecedent, consequent, ok := implication(tmpl.E)
+	if !ok {
+		return nil, nil, diag.Errorf(
+			diag.Proof, a.Span, errNotImplication, tmpl.Name, tmpl.E,
+		)
+	}
+	goal = BinaryOpExpr{
+		Op: Impl,
+		E1: BracketedExpr{Expr: a.Assumed, Span: a.Assumed.Extent()},
+		E2: BracketedExpr{Expr: consequent, Span: consequent.Extent()},
+	}
+	return antecedent, goal, nil
+}
+
+// unbracket returns e without enclosing brackets.
+func unbracket(e Expr) Expr {
+	for {
+		br, ok := e.(BracketedExpr)
+		if !ok {
+			return e
+		}
+		e = br.Expr
+	}
+}
+
+// implication returns the antecedent and consequent of e if it is an
+// implication.
+func implication(e Expr) (Expr, Expr, bool) {
+	switch e := unbracket(e).(type) {
+	case JustifiableBinaryOpExpr:
+		return implication(e.BinaryOpExpr)
+	case BinaryOpExpr:
+		switch e.Op {
+		case Impl:
+			return e.E1, e.E2, true
+		case Fllw:
+			return e.E2, e.E1, true
+		}
+	}
+	return nil, nil, false
+}
+
+// conjunction returns the conjuncts of e.
+func conjunction(e Expr) []Expr {
+	if b, ok := unbracket(e).(BinaryOpExpr); ok && b.Op == And {
+		return append(conjunction(b.E1), conjunction(b.E2)...)
+	}
+	return []Expr{e}
+}
+
+func (ind Induction) String() string {
+	return fmt.Sprintf("induction on %s using %s", ind.Var, ind.Axiom)
+}
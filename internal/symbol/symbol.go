@@ -4,14 +4,15 @@ import (
 	"errors"
 	"fmt"
 
+	"git.sr.ht/~lbnz/i2/internal/diag"
 	"git.sr.ht/~lbnz/i2/internal/truth"
 )
 
 var (
 	errNoTable = errors.New("symbol has no table")
 
-	errIsInvocationLengthMismatch    = "length of parameters does not match parameters in function abstract"
-	errIsInvocationParameterMismatch = "parameter `%s' is of type `%s' but %s `%s' requires type `%s' in position %d"
+	errInvocationArity               = "%s `%s' takes %d arguments but %d were given"
+	errIsInvocationParameterMismatch = "argument `%s' is of type `%s' but %s `%s' requires type `%s' in position %d"
 
 	errFunctionOrTemplateNotDefined = "function/template: `%s' not defined"
 	errVariableNotDefined           = "variable `%s' not defined"
@@ -19,6 +20,18 @@ var (
 	errNonInvocableInvoked          = "`%s' cannot be invoked"
 	errOpOnNonBoolExpr              = "op `%s' cannot be applied to expr `%s' of type `%s`"
 	errInvalidBinaryOp              = "op `%s' is an invalid binary op"
+	errEqualityOnBool               = "op `%s' cannot be applied to propositions; use `===' instead"
+	errEqualityTypeMismatch         = "cannot compare `%s' of type `%s' with `%s' of type `%s'"
+	errTypeNotPredicate             = "type `%s' is not a unary predicate"
+	errNotContradiction             = "proof by contradiction ends in `%s' rather than `false'"
+	errNotImplication               = "cannot assume the antecedent of `%s': `%s' is not an implication"
+	errNotCitable                   = "`%s' cannot be cited: it is not a template or the label of a proof"
+	errNotParameter                 = "`%s' is not a parameter of `%s'"
+	errNotInductionAxiom            = "`%s' is not an axiom of induction: it must be a template of one predicate asserting `base && step ==> conclusion'"
+	errInductionType                = "`%s' requires a predicate of type `%s' but `%s' is of type `%s'"
+	errInductionInstance            = "`%s' cannot be instantiated with the assertion of `%s': %s"
+	errRecursiveDefinition          = "`%s' is defined recursively"
+	errDefinitionTypeMismatch       = "body of `%s' is of type `%s' but it returns `%s'"
 )
 
 type Scope interface {
@@ -41,7 +54,12 @@ func (T Table) Nest(Parent Table) Table {
 type Function struct {
 	IsAxiom bool
 	Sig     FunctionSignature
-	Name    string
+
+	// Body defines the Function, which is unfolded wherever it is invoked,
+	// or is nil if the Function is undefined.
+	Body Expr
+	Name string
+	diag.Span
 }
 
 func (f Function) isPredicate() bool {
@@ -65,7 +83,7 @@ func optionalat(at bool) string {
 
 type Parameter struct {
 	Name string
-	Type
+	Type Type
 }
 
 func (p Parameter) isBool() bool {
@@ -73,48 +91,88 @@ func (p Parameter) isBool() bool {
 }
 
 func (f Function) IsInvocation(params []Parameter) error {
-	if len(params) != len(f.Sig.Params) {
-		return fmt.Errorf(errIsInvocationLengthMismatch)
-	}
-	for i := range params {
-		p, fp := params[i], f.Sig.Params[i]
-		if !(fp.Type == Any || fp.Type == p.Type) {
-			return fmt.Errorf(
-				errIsInvocationParameterMismatch,
-				p.Name, p.Type, "function", f.Name, fp.Type, i,
-			)
-		}
-	}
-	return nil
+	return checkInvocation(
+		"function", f.Name, paramTypes(f.Sig.Params), params,
+	)
 }
 
 func (f Function) String() string {
+	if f.Body != nil {
+		return fmt.Sprintf(
+			"%sfunc %s { %s }", optionalat(f.IsAxiom), f.Sig, f.Body,
+		)
+	}
 	return fmt.Sprintf("%sfunc %s", optionalat(f.IsAxiom), f.Sig)
 }
 
+// unfold returns the analysis of the body of f with its parameters replaced
+// by args.
+func (f Function) unfold(args []Expr, tbl Table) (*AnalysedExpr, error) {
+	m := map[string]Expr{}
+	for i, param := range f.Sig.Params {
+		m[param.Name] = args[i]
+	}
+	return f.Body.replace(m).Analyse(tbl)
+}
+
+// CheckDefinition checks that the body of f, if any, is of its return type
+// and does not invoke f, directly or through the functions in tbl, so that it
+// can always be unfolded.
+func (f Function) CheckDefinition(tbl Table) error {
+	if f.Body == nil {
+		return nil
+	}
+	if invokes(f.Body, f.Name, tbl, map[string]bool{}) {
+		return diag.Errorf(
+			diag.Analysis, f.Body.Extent(), errRecursiveDefinition, f.Name,
+		)
+	}
+	ftbl, err := f.Table()
+	if err != nil {
+		return err
+	}
+	aExpr, err := f.Body.Analyse(ftbl.Nest(tbl))
+	if err != nil {
+		return err
+	}
+	if !aExpr.arg.Type.AssignableTo(f.Sig.Return) {
+		return diag.Errorf(
+			diag.Analysis, f.Body.Extent(), errDefinitionTypeMismatch,
+			f.Name, aExpr.arg.Type, f.Sig.Return,
+		)
+	}
+	return nil
+}
+
 type Template struct {
 	IsAxiom bool
 	Params  []Parameter
 	E       Expr
 	Proofs  []ProofChain
 	Name    string
+	diag.Span
 }
 
 func (t Template) instantiate(args []Expr, tbl Table) (truth.Proposition, error) {
-	m := map[string]Expr{}
 	// by assumption the type checking for args & the template has already
 	// been done, so we can map directly
-	for i, param := range t.Params {
-		m[param.Name] = args[i]
-	}
-	aExpr, err := t.E.replace(m).Analyse(tbl)
+	aExpr, err := t.Substitute(args).Analyse(tbl)
 	if err != nil {
-		fmt.Println("replaced:", t.E.replace(m))
 		return nil, err
 	}
 	return aExpr.P, nil
 }
 
+// Substitute returns the assertion of t with args in place of its
+// parameters.
+func (t Template) Substitute(args []Expr) Expr {
+	m := map[string]Expr{}
+	for i, param := range t.Params {
+		m[param.Name] = args[i]
+	}
+	return t.E.replace(m)
+}
+
 func (t Template) Table() (Table, error) {
 	T := Table{"this": t}
 	for _, p := range t.Params {
@@ -124,19 +182,12 @@ func (t Template) Table() (Table, error) {
 }
 
 func (t Template) IsInvocation(params []Parameter) error {
-	if len(params) != len(t.Params) {
-		return fmt.Errorf(errIsInvocationLengthMismatch)
-	}
-	for i := range params {
-		p, tp := params[i], t.Params[i]
-		if !(tp.Type == Any || tp.Type == p.Type) {
-			return fmt.Errorf(
-				errIsInvocationParameterMismatch,
-				p.Name, p.Type, "template", t.Name, tp.Type, i,
-			)
-		}
-	}
-	return nil
+	return checkInvocation("template", t.Name, paramTypes(t.Params), params)
+}
+
+// Type returns the type of t as a predicate of its parameters.
+func (t Template) Type() Type {
+	return FunctionSignature{t.Params, Bool}.Type()
 }
 
 func (t Template) String() string {
@@ -152,15 +203,17 @@ func (f FunctionSignature) Table() (Table, error) {
 	return nil, errNoTable
 }
 
-type Type string
-
-const (
-	Any  Type = "any"
-	Bool      = "bool"
-)
+// Type returns the function type with the signature.
+func (f FunctionSignature) Type() Type {
+	return FuncType{Params: paramTypes(f.Params), Return: f.Return}
+}
 
-func (p Type) Table() (Table, error) {
-	return nil, errNoTable
+func paramTypes(params []Parameter) []Type {
+	types := make([]Type, len(params))
+	for i := range params {
+		types[i] = params[i].Type
+	}
+	return types
 }
 
 type LocalProof struct {
@@ -170,3 +223,39 @@ type LocalProof struct {
 func (lp LocalProof) Table() (Table, error) {
 	return nil, errNoTable
 }
+
+// QualifiedTerm is a term imported from a module, which stands for itself
+// by its qualified Name however it is referred to.
+type QualifiedTerm struct {
+	Name string
+	Type Type
+}
+
+func (q QualifiedTerm) Table() (Table, error) {
+	return nil, errNoTable
+}
+
+// Qualify returns sym, declared as name in a module, named apart from the
+// symbols of any file importing it: it and the symbols of the module its
+// definition refers to are renamed as names gives.
+func Qualify(sym Scope, name string, names map[string]string) Scope {
+	m := map[string]Expr{}
+	for from, to := range names {
+		m[from] = SimpleExpr{Name: to}
+	}
+	switch sym := sym.(type) {
+	case Function:
+		sym.Name = names[name]
+		if sym.Body != nil {
+			sym.Body = sym.Body.replace(unbound(sym.Sig.Params, m))
+		}
+		return sym
+	case Template:
+		sym.Name = names[name]
+		sym.E = sym.E.replace(unbound(sym.Params, m))
+		return sym
+	case Type:
+		return QualifiedTerm{names[name], sym}
+	}
+	return sym
+}
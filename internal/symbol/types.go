@@ -0,0 +1,110 @@
+package symbol
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Type is the type of a value: a BaseType or a FuncType. Types are parsed
+// once, by the grammar, so that a malformed type is a syntax error.
+type Type interface {
+	// AssignableTo indicates whether a value of the type may be given
+	// where one of type u is required.
+	AssignableTo(u Type) bool
+
+	Scope
+	fmt.Stringer
+}
+
+// BaseType is a type named by an identifier, such as `nat'. Each BaseType is
+// assignable only to itself and to `any', the top type.
+type BaseType string
+
+const (
+	Any  BaseType = "any"
+	Bool BaseType = "bool"
+)
+
+func (b BaseType) AssignableTo(u Type) bool {
+	return u == Any || u == b
+}
+
+func (b BaseType) Table() (Table, error) {
+	return nil, errNoTable
+}
+
+func (b BaseType) String() string {
+	return string(b)
+}
+
+// FuncType is the type of a function or template. Function types are
+// contravariant in their parameters and covariant in their return type.
+type FuncType struct {
+	Params []Type
+	Return Type
+}
+
+func (f FuncType) AssignableTo(u Type) bool {
+	if u == Any {
+		return true
+	}
+	f2, ok := u.(FuncType)
+	if !ok || len(f.Params) != len(f2.Params) {
+		return false
+	}
+	for i := range f.Params {
+		if !f2.Params[i].AssignableTo(f.Params[i]) {
+			return false
+		}
+	}
+	return f.Return.AssignableTo(f2.Return)
+}
+
+func (f FuncType) Table() (Table, error) {
+	return nil, errNoTable
+}
+
+func (f FuncType) String() string {
+	params := make([]string, len(f.Params))
+	for i := range f.Params {
+		params[i] = f.Params[i].String()
+	}
+	return fmt.Sprintf("func(%s) %s", strings.Join(params, ", "), f.Return)
+}
+
+// checkInvocation checks the arguments given to the kind of invocable name,
+// whose parameters are of the types want.
+func checkInvocation(kind, name string, want []Type, args []Parameter) error {
+	if len(args) != len(want) {
+		return &ArgumentError{
+			Position: -1,
+			msg: fmt.Sprintf(
+				errInvocationArity, kind, name, len(want), len(args),
+			),
+		}
+	}
+	for i := range args {
+		if !args[i].Type.AssignableTo(want[i]) {
+			return &ArgumentError{
+				Position: i,
+				msg: fmt.Sprintf(
+					errIsInvocationParameterMismatch,
+					args[i].Name, args[i].Type, kind, name, want[i], i+1,
+				),
+			}
+		}
+	}
+	return nil
+}
+
+// ArgumentError is the error of an invocation with the wrong arguments.
+type ArgumentError struct {
+	// Position is the index of the offending argument, or -1 if the number
+	// of arguments is wrong.
+	Position int
+	msg      string
+}
+
+func (e *ArgumentError) Error() string {
+	return e.msg
+}
@@ -0,0 +1,32 @@
+package symbol
+
+import "testing"
+
+func TestAssignableTo(t *testing.T) {
+	const nat, set BaseType = "nat", "set"
+	fn := func(ret Type, params ...Type) Type {
+		return FuncType{Params: params, Return: ret}
+	}
+	for _, c := range []struct {
+		t, u Type
+		want bool
+	}{
+		{nat, nat, true},
+		{nat, Any, true},
+		{Any, nat, false},
+		{nat, set, false},
+		{fn(Bool, nat), Any, true},
+		{fn(Bool, nat), fn(Bool, nat), true},
+		{fn(Bool, Any), fn(Bool, nat), true},
+		{fn(Bool, nat), fn(Bool, Any), false},
+		{fn(nat, nat), fn(Any, nat), true},
+		{fn(nat, nat), fn(Bool, nat), false},
+		{fn(Bool, nat, nat), fn(Bool, nat), false},
+		{fn(Bool, fn(Bool, nat)), fn(Bool, fn(Bool, Any)), true},
+		{fn(Bool), fn(Bool), true},
+	} {
+		if got := c.t.AssignableTo(c.u); got != c.want {
+			t.Errorf("%s assignable to %s: got %t", c.t, c.u, got)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package truth
+
+import (
+	"git.sr.ht/~lbnz/i2/internal/sat"
+)
+
+// encoder performs the Tseitin transformation of Propositions into the
+// clauses of a sat.Solver, so that each Proposition is represented by a
+// literal equisatisfiable with it. Atomic formulas and quantified
+// subformulas are encoded as opaque atoms, though the atomic formulas are
+// recorded so that models inconsistent with equality can be excluded.
+type encoder struct {
+	s      *sat.Solver
+	top    sat.Lit
+	atoms  map[Variable]sat.Var
+	order  []Variable
+	theory []Proposition
+}
+
+func newEncoder() *encoder {
+	s := sat.New()
+	top := s.NewVar().Pos()
+	s.AddClause(top)
+	return &encoder{s: s, top: top, atoms: map[Variable]sat.Var{}}
+}
+
+func (e *encoder) atom(v Variable) sat.Lit {
+	if x, ok := e.atoms[v]; ok {
+		return x.Pos()
+	}
+	x := e.s.NewVar()
+	e.atoms[v] = x
+	e.order = append(e.order, v)
+	return x.Pos()
+}
+
+// implies returns a literal x with x ⟺ (a ⟹ b), folding constants where
+// possible.
+func (e *encoder) implies(a, b sat.Lit) sat.Lit {
+	switch {
+	case a == e.top.Not() || b == e.top || a == b:
+		return e.top
+	case a == e.top:
+		return b
+	case b == e.top.Not():
+		return a.Not()
+	}
+	x := e.s.NewVar().Pos()
+	e.s.AddClause(x.Not(), a.Not(), b)
+	e.s.AddClause(x, a)
+	e.s.AddClause(x, b.Not())
+	return x
+}
+
+// state returns the assignment of the atoms in the solver's model.
+func (e *encoder) state() State {
+	m := State{}
+	for _, v := range e.order {
+		m[v] = e.s.Value(e.atoms[v])
+	}
+	return m
+}
+
+// satisfy returns a state in which p holds, or nil if there is none.
+func satisfy(p Proposition) (State, error) {
+	e := newEncoder()
+	x, err := p.encode(e)
+	if err != nil {
+		return nil, err
+	}
+	if !e.s.AddClause(x) || !e.solve() {
+		return nil, nil
+	}
+	return e.state(), nil
+}
@@ -0,0 +1,225 @@
+package truth
+
+import (
+	"fmt"
+
+	"git.sr.ht/~lbnz/i2/internal/sat"
+)
+
+// equality is the atomic formula that two Terms are equal. Equalities are
+// interpreted by the theory of equality with uninterpreted functions: they
+// are reflexive, symmetric and transitive, and preserved by the application of
+// function and predicate symbols.
+type equality struct {
+	a, b Term
+}
+
+// Eq returns the Proposition that a and b are equal.
+func Eq(a, b Term) Proposition {
+	return equality{a, b}
+}
+
+// atom returns the propositional atom standing for the equality, which is
+// the same for both of its orientations.
+func (eq equality) atom() Variable {
+	a, b := eq.a.String(), eq.b.String()
+	if b < a {
+		a, b = b, a
+	}
+	return Variable(fmt.Sprintf("%s = %s", a, b))
+}
+
+func (eq equality) eval(m State) bool {
+	return m[eq.atom()]
+}
+
+func (eq equality) free() []Variable {
+	return termsFree([]Term{eq.a, eq.b})
+}
+
+func (eq equality) replace(a, b Variable) Proposition {
+	return eq.substitute(a, b)
+}
+
+func (eq equality) substitute(a Variable, t Term) Proposition {
+	return equality{eq.a.termSubstitute(a, t), eq.b.termSubstitute(a, t)}
+}
+
+func (eq equality) needsBrackets(op operator) bool {
+	return op.precedence() >= precNegation
+}
+
+func (eq equality) equals(p Proposition) bool {
+	eq2, ok := p.(equality)
+	if !ok {
+		return false
+	}
+	return eq.atom() == eq2.atom()
+}
+
+func (eq equality) encode(e *encoder) (sat.Lit, error) {
+	return e.theoryAtom(eq.atom(), eq), nil
+}
+
+func (eq equality) String() string {
+	return fmt.Sprintf("%s = %s", eq.a, eq.b)
+}
+
+// congruence is the congruence closure of a set of equalities: the finest
+// equivalence relation on terms that contains them and is preserved by
+// function application.
+type congruence struct {
+	parent map[string]string
+	apps   []application
+}
+
+func newCongruence() *congruence {
+	return &congruence{parent: map[string]string{}}
+}
+
+// add adds t and its subterms to the terms related.
+func (c *congruence) add(t Term) {
+	key := t.String()
+	if _, ok := c.parent[key]; ok {
+		return
+	}
+	c.parent[key] = key
+	if app, ok := t.(application); ok {
+		c.apps = append(c.apps, app)
+		for _, arg := range app.args {
+			c.add(arg)
+		}
+	}
+}
+
+func (c *congruence) find(key string) string {
+	for c.parent[key] != key {
+		c.parent[key] = c.parent[c.parent[key]]
+		key = c.parent[key]
+	}
+	return key
+}
+
+// union merges the classes of a and b, reporting whether they were distinct.
+func (c *congruence) union(a, b string) bool {
+	ra, rb := c.find(a), c.find(b)
+	if ra == rb {
+		return false
+	}
+	c.parent[ra] = rb
+	return true
+}
+
+func (c *congruence) congruent(s, t []Term) bool {
+	if len(s) != len(t) {
+		return false
+	}
+	for i := range s {
+		if c.find(s[i].String()) != c.find(t[i].String()) {
+			return false
+		}
+	}
+	return true
+}
+
+// close merges the classes of applications of the same function to
+// equivalent arguments until there are none left to merge.
+func (c *congruence) close() {
+	for changed := true; changed; {
+		changed = false
+		for i := range c.apps {
+			for j := i + 1; j < len(c.apps); j++ {
+				s, t := c.apps[i], c.apps[j]
+				if s.name == t.name && c.congruent(s.args, t.args) &&
+					c.union(s.String(), t.String()) {
+					changed = true
+				}
+			}
+		}
+	}
+}
+
+// theoryAtom returns the literal standing for the atom v of the atomic
+// formula p, recording p for the theory check.
+func (e *encoder) theoryAtom(v Variable, p Proposition) sat.Lit {
+	if _, ok := e.atoms[v]; !ok {
+		e.theory = append(e.theory, p)
+	}
+	return e.atom(v)
+}
+
+// conflict returns a clause excluding the model of the solver if the atomic
+// formulas are inconsistent in it with the theory of equality, or nil if they
+// are consistent.
+func (e *encoder) conflict() []sat.Lit {
+	var eqs, neqs []equality
+	var fns []function
+	for _, p := range e.theory {
+		switch p := p.(type) {
+		case equality:
+			if e.s.Value(e.atoms[p.atom()]) {
+				eqs = append(eqs, p)
+			} else {
+				neqs = append(neqs, p)
+			}
+		case function:
+			fns = append(fns, p)
+		}
+	}
+	if len(eqs) == 0 && len(neqs) == 0 {
+		return nil
+	}
+	c := newCongruence()
+	for _, p := range e.theory {
+		switch p := p.(type) {
+		case equality:
+			c.add(p.a)
+			c.add(p.b)
+		case function:
+			for _, arg := range p.args {
+				c.add(arg)
+			}
+		}
+	}
+	for _, eq := range eqs {
+		c.union(eq.a.String(), eq.b.String())
+	}
+	c.close()
+	// the equalities together entail the violated literal
+	block := make([]sat.Lit, len(eqs), len(eqs)+2)
+	for i, eq := range eqs {
+		block[i] = e.atoms[eq.atom()].Neg()
+	}
+	for _, neq := range neqs {
+		if c.find(neq.a.String()) == c.find(neq.b.String()) {
+			return append(block, e.atoms[neq.atom()].Pos())
+		}
+	}
+	for i := range fns {
+		for j := range fns {
+			p, q := fns[i], fns[j]
+			pv, qv := e.atoms[p.atom()], e.atoms[q.atom()]
+			if p.name == q.name && e.s.Value(pv) && !e.s.Value(qv) &&
+				c.congruent(p.args, q.args) {
+				return append(block, pv.Neg(), qv.Pos())
+			}
+		}
+	}
+	return nil
+}
+
+// solve decides the satisfiability of the clauses of the encoder modulo the
+// theory of equality, excluding inconsistent models until it finds a
+// consistent one or there are none.
+func (e *encoder) solve() bool {
+	for e.s.Solve() {
+		block := e.conflict()
+		if block == nil {
+			return true
+		}
+		if !e.s.AddClause(block...) {
+			return false
+		}
+	}
+	return false
+}
@@ -0,0 +1,270 @@
+package truth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ErrUnknown is wrapped by the error returned when the validity of a
+// quantified Proposition cannot be established within the bounds of the
+// first-order procedure.
+var ErrUnknown = errors.New("cannot decide")
+
+const (
+	// maxInstances bounds the number of ground instances generated for a
+	// single refutation.
+	maxInstances = 20000
+
+	// maxDepth bounds the nesting of function symbols in the Herbrand
+	// universe explored.
+	maxDepth = 3
+)
+
+func quantified(p Proposition) bool {
+	switch p := p.(type) {
+	case lambda:
+		return true
+	case implication:
+		return quantified(p.antecedent) || quantified(p.consequent)
+	default:
+		return false
+	}
+}
+
+// rectify renames the bound variables in p so that they are pairwise distinct
+// and distinct from the free variables. The primes in the new names cannot
+// occur in i2 identifiers.
+func rectify(p Proposition, n *int) Proposition {
+	switch p := p.(type) {
+	case implication:
+		return implication{rectify(p.antecedent, n), rectify(p.consequent, n)}
+	case lambda:
+		b := Variable(fmt.Sprintf("%s'%d", p.v, *n))
+		*n++
+		return lambda{p.q, b, rectify(p.scope.substitute(p.v, b), n)}
+	default:
+		return p
+	}
+}
+
+// skolemize strips the quantifier prefix from p, which must be in prenex
+// normal form, replacing the existentially quantified variables with Skolem
+// functions of the universally quantified ones preceding them. It returns the
+// matrix along with the universally quantified variables.
+func skolemize(p Proposition) (Proposition, []Variable) {
+	universals := []Variable{}
+	for n := 0; ; n++ {
+		λ, ok := p.(lambda)
+		if !ok {
+			return p, universals
+		}
+		switch λ.q {
+		case universal:
+			universals = append(universals, λ.v)
+			p = λ.scope
+		case existential:
+			args := make([]Term, len(universals))
+			for i := range universals {
+				args[i] = universals[i]
+			}
+			sk := Apply(fmt.Sprintf("sk'%d", n), args...)
+			p = λ.scope.substitute(λ.v, sk)
+		}
+	}
+}
+
+// herbrand is a finite approximation of the Herbrand universe of a matrix.
+type herbrand struct {
+	terms []Term
+	seen  map[string]bool
+	funcs map[string]int
+}
+
+func newHerbrand(matrix Proposition, universals []Variable) *herbrand {
+	h := &herbrand{seen: map[string]bool{}, funcs: map[string]int{}}
+	bound := map[Variable]bool{}
+	for _, u := range universals {
+		bound[u] = true
+	}
+	h.collect(matrix, bound)
+	if len(h.terms) == 0 {
+		h.add(Variable("c'0"))
+	}
+	return h
+}
+
+func (h *herbrand) add(t Term) {
+	if s := t.String(); !h.seen[s] {
+		h.seen[s] = true
+		h.terms = append(h.terms, t)
+	}
+}
+
+// collect adds the ground terms in p to the universe, and records the
+// function symbols used to build terms in it.
+func (h *herbrand) collect(p Proposition, bound map[Variable]bool) {
+	switch p := p.(type) {
+	case implication:
+		h.collect(p.antecedent, bound)
+		h.collect(p.consequent, bound)
+	case function:
+		for _, t := range p.args {
+			h.collectTerm(t, bound)
+		}
+	case equality:
+		h.collectTerm(p.a, bound)
+		h.collectTerm(p.b, bound)
+	}
+}
+
+func (h *herbrand) collectTerm(t Term, bound map[Variable]bool) bool {
+	switch t := t.(type) {
+	case Variable:
+		if bound[t] {
+			return false
+		}
+	case application:
+		ground := true
+		for _, arg := range t.args {
+			if !h.collectTerm(arg, bound) {
+				ground = false
+			}
+		}
+		if len(t.args) > 0 {
+			h.funcs[t.name] = len(t.args)
+		}
+		if !ground {
+			return false
+		}
+	}
+	h.add(t)
+	return true
+}
+
+// expand adds the terms one function application deeper than the present
+// ones, stopping early if more than budget would be added.
+func (h *herbrand) expand(budget int) {
+	names := make([]string, 0, len(h.funcs))
+	for name := range h.funcs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	current := append([]Term{}, h.terms...)
+	for _, name := range names {
+		tuples(current, h.funcs[name], func(args []Term) bool {
+			h.add(application{name, append([]Term{}, args...)})
+			return len(h.terms) < budget
+		})
+	}
+}
+
+// tuples calls f with each n-tuple over terms, stopping if f returns false.
+func tuples(terms []Term, n int, f func([]Term) bool) bool {
+	tuple := make([]Term, n)
+	var rec func(int) bool
+	rec = func(i int) bool {
+		if i == n {
+			return f(tuple)
+		}
+		for _, t := range terms {
+			tuple[i] = t
+			if !rec(i + 1) {
+				return false
+			}
+		}
+		return true
+	}
+	return rec(0)
+}
+
+type refutation struct {
+	// refuted indicates that the Proposition is unsatisfiable.
+	refuted bool
+
+	// saturated indicates that the Herbrand universe was exhausted without
+	// a refutation, so model is a model of the Proposition.
+	saturated bool
+	model     State
+}
+
+// refute searches for a refutation of p by generating ground instances of
+// its Skolemized matrix over increasingly deep Herbrand universes and
+// checking their conjunction for satisfiability.
+func refute(ctx context.Context, p Proposition) (*refutation, error) {
+	n := 0
+	matrix, universals := skolemize(prenex(rectify(p, &n)))
+	h := newHerbrand(matrix, universals)
+	e := newEncoder()
+	instantiated := map[string]bool{}
+	count := 0
+	for depth := 0; ; depth++ {
+		complete := tuples(h.terms, len(universals), func(args []Term) bool {
+			key := termsKey(args)
+			if instantiated[key] {
+				return true
+			}
+			instantiated[key] = true
+			inst := matrix
+			for i, u := range universals {
+				inst = inst.substitute(u, args[i])
+			}
+			lit, err := inst.encode(e)
+			if err != nil {
+				return false
+			}
+			e.s.AddClause(lit)
+			count++
+			return count < maxInstances && ctx.Err() == nil
+		})
+		if !e.solve() {
+			return &refutation{refuted: true}, nil
+		}
+		if complete && (len(h.funcs) == 0 || len(universals) == 0) {
+			return &refutation{saturated: true, model: e.state()}, nil
+		}
+		switch {
+		case ctx.Err() != nil:
+			return nil, fmt.Errorf("%w: %s", ErrUnknown, ctx.Err())
+		case !complete || depth == maxDepth:
+			return &refutation{}, nil
+		}
+		h.expand(maxInstances)
+	}
+}
+
+func termsKey(terms []Term) string {
+	sarr := make([]string, len(terms))
+	for i := range terms {
+		sarr[i] = terms[i].String()
+	}
+	return strings.Join(sarr, "\x00")
+}
+
+// decideFirstOrder decides p by attempting to refute its negation (showing
+// it valid) and p itself (showing it unsatisfiable).
+func decideFirstOrder(ctx context.Context, p Proposition) (bool, error) {
+	neg, err := refute(ctx, Not(p))
+	if err != nil {
+		return false, err
+	}
+	if neg.refuted {
+		return true, nil
+	}
+	pos, err := refute(ctx, p)
+	if err != nil {
+		return false, err
+	}
+	if pos.refuted {
+		return false, nil
+	}
+	if neg.saturated && pos.saturated {
+		return false, &conflict{A: pos.model, B: neg.model, aval: true}
+	}
+	return false, fmt.Errorf(
+		"%w: `%s' within %d instances of depth %d",
+		ErrUnknown, p, maxInstances, maxDepth,
+	)
+}
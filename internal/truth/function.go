@@ -1,33 +1,36 @@
 package truth
 
 import (
-	"fmt"
-	"strings"
+	"git.sr.ht/~lbnz/i2/internal/sat"
 )
 
+// function is the application of a predicate symbol to Terms, i.e. an atomic
+// formula of first-order logic.
 type function struct {
 	name string
-	vars []Variable
+	args []Term
 }
 
-func (fn function) eval(_ state) bool {
-	panic("cannot eval function")
+// atom returns the propositional atom standing for the function when its
+// arguments are treated as opaque.
+func (fn function) atom() Variable {
+	return Variable(fn.String())
+}
+
+func (fn function) eval(m State) bool {
+	return m[fn.atom()]
 }
 
 func (fn function) free() []Variable {
-	return []Variable(fn.vars)
+	return termsFree(fn.args)
 }
 
 func (fn function) replace(a, b Variable) Proposition {
-	vars := make([]Variable, len(fn.vars))
-	for i, v := range fn.vars {
-		nv, ok := v.replace(a, b).(Variable)
-		if !ok {
-			panic("replace returned non-variable")
-		}
-		vars[i] = nv
-	}
-	return function{fn.name, vars}
+	return fn.substitute(a, b)
+}
+
+func (fn function) substitute(a Variable, t Term) Proposition {
+	return function{fn.name, termsSubstitute(fn.args, a, t)}
 }
 
 func (fn function) needsBrackets(_ operator) bool {
@@ -39,21 +42,13 @@ func (fn function) equals(p Proposition) bool {
 	if !ok {
 		return false
 	}
-	if len(fn.vars) != len(fn2.vars) {
-		return false
-	}
-	for i := range fn.vars {
-		if !fn.vars[i].equals(fn2.vars[i]) {
-			return false
-		}
-	}
-	return fn.name == fn2.name
+	return fn.name == fn2.name && termsEqual(fn.args, fn2.args)
+}
+
+func (fn function) encode(e *encoder) (sat.Lit, error) {
+	return e.theoryAtom(fn.atom(), fn), nil
 }
 
 func (fn function) String() string {
-	sarr := make([]string, len(fn.vars))
-	for i := range fn.vars {
-		sarr[i] = string(fn.vars[i])
-	}
-	return fmt.Sprintf("%s(%s)", fn.name, strings.Join(sarr, ", "))
+	return formatApplication(fn.name, fn.args)
 }
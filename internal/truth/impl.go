@@ -1,5 +1,7 @@
 package truth
 
+import "git.sr.ht/~lbnz/i2/internal/sat"
+
 type implication struct {
 	antecedent, consequent Proposition
 }
@@ -20,7 +22,7 @@ func (impl implication) free() []Variable {
 	return vars
 }
 
-func (impl implication) eval(m state) bool {
+func (impl implication) eval(m State) bool {
 	return !impl.antecedent.eval(m) || impl.consequent.eval(m)
 }
 
@@ -74,6 +76,13 @@ func (impl implication) replace(a, b Variable) Proposition {
 	}
 }
 
+func (impl implication) substitute(a Variable, t Term) Proposition {
+	return implication{
+		impl.antecedent.substitute(a, t),
+		impl.consequent.substitute(a, t),
+	}
+}
+
 func (impl implication) needsBrackets(op operator) bool {
 	if r := resolveImplOp(impl); r.operator.precedence() <= op.precedence() {
 		return true
@@ -90,6 +99,18 @@ func (impl implication) equals(p Proposition) bool {
 		impl.consequent.equals(impl2.consequent)
 }
 
+func (impl implication) encode(e *encoder) (sat.Lit, error) {
+	a, err := impl.antecedent.encode(e)
+	if err != nil {
+		return 0, err
+	}
+	b, err := impl.consequent.encode(e)
+	if err != nil {
+		return 0, err
+	}
+	return e.implies(a, b), nil
+}
+
 func (impl implication) String() string {
 	return formatImplOp(impl)
 }
@@ -2,6 +2,8 @@ package truth
 
 import (
 	"fmt"
+
+	"git.sr.ht/~lbnz/i2/internal/sat"
 )
 
 type quantifier string
@@ -47,8 +49,32 @@ func (λ lambda) free() []Variable {
 	return vars
 }
 
-func (λ lambda) eval(_ state) bool {
-	panic("lambdas cannot eval")
+// atom returns the propositional atom standing for the lambda when its
+// quantified structure is treated as opaque. Alpha-equivalent lambdas share
+// the same atom.
+func (λ lambda) atom() Variable {
+	return Variable(canonical(λ, 0).String())
+}
+
+// canonical returns the Proposition alpha-equivalent to p whose bound
+// variables are named by the depth at which they are bound.
+func canonical(p Proposition, depth int) Proposition {
+	switch p := p.(type) {
+	case implication:
+		return implication{
+			canonical(p.antecedent, depth),
+			canonical(p.consequent, depth),
+		}
+	case lambda:
+		v := Variable(fmt.Sprintf("'%d", depth))
+		return lambda{p.q, v, canonical(p.scope.replace(p.v, v), depth+1)}
+	default:
+		return p
+	}
+}
+
+func (λ lambda) eval(m State) bool {
+	return m[λ.atom()]
 }
 
 func occursFreely(a Variable, D Proposition) bool {
@@ -64,19 +90,61 @@ func (λ lambda) reductionVariable(D Proposition) Variable {
 	if !occursFreely(λ.v, D) {
 		return λ.v
 	}
-	i, v := 0, λ.v
-	for occursFreely(v, λ.scope) || occursFreely(v, D) {
-		// NB: the assumption here is that bound occurrences in nested
-		// lambdas are irrelevant.
-		v = Variable(fmt.Sprintf("%s%d", λ.v, i))
-		i++
+	return fresh(λ.v, λ.scope, D)
+}
+
+// fresh returns a variable based on v that occurs freely in none of the
+// given Propositions.
+func fresh(v Variable, avoid ...Proposition) Variable {
+	for i, u := 0, v; ; i++ {
+		clash := false
+		for _, D := range avoid {
+			if occursFreely(u, D) {
+				clash = true
+				break
+			}
+		}
+		if !clash {
+			return u
+		}
+		u = Variable(fmt.Sprintf("%s%d", v, i))
 	}
-	return v
+}
+
+// rename returns the lambda alpha-equivalent to λ whose bound variable
+// occurs in none of vars, or λ itself if this is already the case.
+func (λ lambda) rename(vars []Variable) lambda {
+	clash := false
+	for _, v := range vars {
+		if v == λ.v {
+			clash = true
+		}
+	}
+	if !clash {
+		return λ
+	}
+	avoid := []Proposition{λ.scope}
+	for _, v := range vars {
+		avoid = append(avoid, v)
+	}
+	b := fresh(λ.v, avoid...)
+	return lambda{λ.q, b, λ.scope.replace(λ.v, b)}
 }
 
 func (λ lambda) replace(a, b Variable) Proposition {
-	// FIXME: this is not working
-	return λ
+	if a == λ.v || !occursFreely(a, λ.scope) {
+		return λ
+	}
+	λ = λ.rename([]Variable{b})
+	return lambda{λ.q, λ.v, λ.scope.replace(a, b)}
+}
+
+func (λ lambda) substitute(a Variable, t Term) Proposition {
+	if a == λ.v || !occursFreely(a, λ.scope) {
+		return λ
+	}
+	λ = λ.rename(t.termFree())
+	return lambda{λ.q, λ.v, λ.scope.substitute(a, t)}
 }
 
 func (λ lambda) needsBrackets(_ operator) bool {
@@ -88,7 +156,19 @@ func (λ lambda) equals(p Proposition) bool {
 	if !ok {
 		return false
 	}
-	return λ.q == λ2.q && λ.v == λ2.v && λ.scope.equals(λ2.scope)
+	if λ.q != λ2.q {
+		return false
+	}
+	if λ.v == λ2.v {
+		return λ.scope.equals(λ2.scope)
+	}
+	// compare up to the renaming of the bound variables
+	v := fresh(λ.v, λ.scope, λ2.scope)
+	return λ.scope.replace(λ.v, v).equals(λ2.scope.replace(λ2.v, v))
+}
+
+func (λ lambda) encode(e *encoder) (sat.Lit, error) {
+	return e.atom(λ.atom()), nil
 }
 
 func (λ lambda) String() string {
@@ -275,6 +275,6 @@ func Existential(v Variable, p Proposition) Proposition {
 	return buildLambda(existential, v, p)
 }
 
-func Func(name string, vars ...Variable) Proposition {
-	return function{name, vars}
+func Func(name string, args ...Term) Proposition {
+	return function{name, args}
 }
@@ -0,0 +1,331 @@
+package truth
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// BoolSort is the SMT-LIB sort of Propositions.
+const BoolSort = "Bool"
+
+// Rank is the sorts of the arguments and result of a function symbol.
+type Rank struct {
+	Args   []string
+	Result string
+}
+
+// Signature gives the SMT-LIB sorts of the symbols of a Proposition. Every
+// sort other than Bool is defined as an alias of Top, so that a term may be
+// given wherever an argument of any such sort is required, just as a value of
+// any type may be given where one of type `any' is.
+type Signature struct {
+	Top   string
+	Vars  map[Variable]string
+	Funcs map[string]Rank
+}
+
+// SMTLIB returns the SMT-LIB 2 script asserting the negation of p, which is
+// unsatisfiable exactly if p is valid. The symbols that sig does not give are
+// of sort Bool where they stand for Propositions, and Top otherwise.
+func SMTLIB(p Proposition, sig Signature) string {
+	s := &smtScript{
+		sig:    sig,
+		sorts:  map[string]bool{},
+		consts: map[Variable]string{},
+		ranks:  map[string]Rank{},
+	}
+	s.prop(p, map[Variable]string{})
+	var b strings.Builder
+	b.WriteString("(set-logic ALL)\n")
+	if len(s.sorts) > 0 {
+		fmt.Fprintf(&b, "(declare-sort %s 0)\n", smtSymbol(sig.Top))
+		sorts := make([]string, 0, len(s.sorts))
+		for name := range s.sorts {
+			if name != sig.Top {
+				sorts = append(sorts, name)
+			}
+		}
+		sort.Strings(sorts)
+		for _, name := range sorts {
+			fmt.Fprintf(&b, "(define-sort %s () %s)\n",
+				smtSymbol(name), smtSymbol(sig.Top))
+		}
+	}
+	for _, name := range s.funcs {
+		r := s.ranks[name]
+		args := make([]string, len(r.Args))
+		for i := range r.Args {
+			args[i] = smtSymbol(r.Args[i])
+		}
+		fmt.Fprintf(&b, "(declare-fun %s (%s) %s)\n", smtSymbol(name),
+			strings.Join(args, " "), smtSymbol(r.Result))
+	}
+	for _, v := range s.order {
+		fmt.Fprintf(&b, "(declare-const %s %s)\n",
+			smtSymbol(string(v)), smtSymbol(s.consts[v]))
+	}
+	fmt.Fprintf(&b, "(assert (not %s))\n(check-sat)\n", s.expr(p))
+	return b.String()
+}
+
+// smtScript collects the declarations of the symbols of a Proposition.
+type smtScript struct {
+	sig Signature
+
+	// sorts are the sorts used other than Bool.
+	sorts map[string]bool
+
+	// consts are the sorts of the free variables, declared in order.
+	consts map[Variable]string
+	order  []Variable
+
+	// ranks are those of the function symbols, declared in the order of
+	// funcs.
+	ranks map[string]Rank
+	funcs []string
+}
+
+func (s *smtScript) use(sort string) {
+	if sort != BoolSort {
+		s.sorts[sort] = true
+	}
+}
+
+// varSort returns the sort of the variable v, bound over scope if it is not
+// nil.
+func (s *smtScript) varSort(v Variable, scope Proposition) string {
+	if sort, ok := s.sig.Vars[v]; ok {
+		return sort
+	}
+	if scope != nil && propositional(v, scope) {
+		return BoolSort
+	}
+	return s.sig.Top
+}
+
+func (s *smtScript) declare(v Variable, sort string) {
+	if _, ok := s.consts[v]; ok {
+		return
+	}
+	if given, ok := s.sig.Vars[v]; ok {
+		sort = given
+	}
+	s.use(sort)
+	s.consts[v] = sort
+	s.order = append(s.order, v)
+}
+
+// prop declares the symbols of p, in which the variables of bound are bound
+// to their sorts.
+func (s *smtScript) prop(p Proposition, bound map[Variable]string) {
+	switch p := p.(type) {
+	case Variable:
+		if _, ok := bound[p]; !ok {
+			s.declare(p, BoolSort)
+		}
+	case function:
+		s.apply(p.name, p.args, BoolSort, bound)
+	case equality:
+		s.term(p.a, "", bound)
+		s.term(p.b, "", bound)
+	case implication:
+		s.prop(p.antecedent, bound)
+		s.prop(p.consequent, bound)
+	case lambda:
+		scope := map[Variable]string{}
+		for v, sort := range bound {
+			scope[v] = sort
+		}
+		scope[p.v] = s.varSort(p.v, p.scope)
+		s.use(scope[p.v])
+		s.prop(p.scope, scope)
+	}
+}
+
+// term declares the symbols of t, which is of sort want if it is not empty.
+func (s *smtScript) term(t Term, want string, bound map[Variable]string) {
+	switch t := t.(type) {
+	case Variable:
+		if _, ok := bound[t]; ok {
+			return
+		}
+		if want == "" {
+			want = s.sig.Top
+		}
+		s.declare(t, want)
+	case application:
+		if want == "" {
+			want = s.sig.Top
+		}
+		s.apply(t.name, t.args, want, bound)
+	}
+}
+
+// apply declares the function symbol name, applied to args with a result of
+// sort result unless the Signature gives another, and the symbols of args.
+func (s *smtScript) apply(name string, args []Term, result string,
+	bound map[Variable]string) {
+	r, ok := s.ranks[name]
+	if !ok {
+		if r, ok = s.sig.Funcs[name]; !ok {
+			r = Rank{Args: make([]string, len(args)), Result: result}
+			for i, arg := range args {
+				r.Args[i] = s.termSort(arg, bound)
+			}
+		}
+		for _, sort := range r.Args {
+			s.use(sort)
+		}
+		s.use(r.Result)
+		s.ranks[name] = r
+		s.funcs = append(s.funcs, name)
+	}
+	for i, arg := range args {
+		want := ""
+		if i < len(r.Args) {
+			want = r.Args[i]
+		}
+		s.term(arg, want, bound)
+	}
+}
+
+// termSort returns the sort of t, so far as it is known.
+func (s *smtScript) termSort(t Term, bound map[Variable]string) string {
+	switch t := t.(type) {
+	case Variable:
+		if sort, ok := bound[t]; ok {
+			return sort
+		}
+		if sort, ok := s.consts[t]; ok {
+			return sort
+		}
+		return s.varSort(t, nil)
+	case application:
+		if r, ok := s.ranks[t.name]; ok {
+			return r.Result
+		}
+		if r, ok := s.sig.Funcs[t.name]; ok {
+			return r.Result
+		}
+	}
+	return s.sig.Top
+}
+
+// propositional indicates whether v occurs free in p as a Proposition.
+func propositional(v Variable, p Proposition) bool {
+	switch p := p.(type) {
+	case Variable:
+		return p == v
+	case implication:
+		return propositional(v, p.antecedent) ||
+			propositional(v, p.consequent)
+	case lambda:
+		return p.v != v && propositional(v, p.scope)
+	default:
+		return false
+	}
+}
+
+// expr returns the SMT-LIB term for p.
+func (s *smtScript) expr(p Proposition) string {
+	switch p := p.(type) {
+	case Constant:
+		return p.String()
+	case Variable:
+		return smtSymbol(string(p))
+	case function:
+		return smtApplication(p.name, p.args)
+	case equality:
+		return fmt.Sprintf("(= %s %s)", smtTerm(p.a), smtTerm(p.b))
+	case implication:
+		op := smtOperation(p)
+		if op.operator == opNegation {
+			return fmt.Sprintf("(not %s)", s.expr(op.A))
+		}
+		return fmt.Sprintf("(%s %s %s)", map[operator]string{
+			opConjunction: "and",
+			opDisjunction: "or",
+			opImplication: "=>",
+			opEquivalence: "=",
+		}[op.operator], s.expr(op.A), s.expr(op.B))
+	case lambda:
+		q := "forall"
+		if p.q == existential {
+			q = "exists"
+		}
+		return fmt.Sprintf("(%s ((%s %s)) %s)", q, smtSymbol(string(p.v)),
+			smtSymbol(s.varSort(p.v, p.scope)), s.expr(p.scope))
+	default:
+		panic(fmt.Sprintf("unknown proposition %s", p))
+	}
+}
+
+// smtOperation resolves the connective that impl stands for. Unlike
+// resolveImplOp, it leaves an implication from a conjunction or a negation
+// as such rather than reading it as a disjunction, and keeps double
+// negations, since the script need not be canonical.
+func smtOperation(impl implication) *operation {
+	for _, resolve := range []func(Proposition) (*operation, bool){
+		resolveEqv, resolveAnd, resolveNot,
+	} {
+		if r, ok := resolve(impl); ok {
+			return r
+		}
+	}
+	if _, ok := resolveNot(impl.antecedent); !ok {
+		if r, ok := resolveOr(impl); ok {
+			return r
+		}
+	}
+	return &operation{opImplication, impl.antecedent, impl.consequent}
+}
+
+func smtTerm(t Term) string {
+	switch t := t.(type) {
+	case Variable:
+		return smtSymbol(string(t))
+	case application:
+		return smtApplication(t.name, t.args)
+	default:
+		panic(fmt.Sprintf("unknown term %s", t))
+	}
+}
+
+func smtApplication(name string, args []Term) string {
+	if len(args) == 0 {
+		return smtSymbol(name)
+	}
+	sarr := make([]string, len(args))
+	for i := range args {
+		sarr[i] = smtTerm(args[i])
+	}
+	return fmt.Sprintf("(%s %s)", smtSymbol(name), strings.Join(sarr, " "))
+}
+
+var smtSimpleSymbol = regexp.MustCompile(
+	`^[a-zA-Z~!@$%^&*_+=<>.?/-][a-zA-Z0-9~!@$%^&*_+=<>.?/-]*$`,
+)
+
+// smtReserved are the reserved words of SMT-LIB 2, which must be quoted to
+// be used as symbols.
+var smtReserved = map[string]bool{
+	"!": true, "_": true, "as": true, "BINARY": true, "DECIMAL": true,
+	"exists": true, "forall": true, "HEXADECIMAL": true, "let": true,
+	"match": true, "NUMERAL": true, "par": true, "STRING": true,
+	"assert": true, "check-sat": true, "declare-const": true,
+	"declare-fun": true, "declare-sort": true, "define-fun": true,
+	"define-sort": true, "exit": true, "get-model": true, "pop": true,
+	"push": true, "set-info": true, "set-logic": true, "set-option": true,
+}
+
+// smtSymbol returns name as an SMT-LIB symbol, quoting it unless it is a
+// simple symbol. The characters that cannot be quoted, which occur in the
+// names of opaque compound arguments such as `a || b', are replaced.
+func smtSymbol(name string) string {
+	if smtSimpleSymbol.MatchString(name) && !smtReserved[name] {
+		return name
+	}
+	return "|" + strings.NewReplacer("|", "¦", `\`, "∖").Replace(name) + "|"
+}
@@ -0,0 +1,283 @@
+package truth
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"testing/quick"
+)
+
+// The tests in this file check substitution, free variables and
+// alpha-equivalence against a reference implementation on de Bruijn
+// (nameless) terms, in which none of these require renaming.
+
+var (
+	individuals = []Variable{"x", "y", "z"}
+	atoms       = []Variable{"p", "q"}
+)
+
+type dbNode struct {
+	kind  string // lam, impl, pred, app, bvar, fvar, atom, const
+	name  string
+	index int
+	kids  []*dbNode
+}
+
+func (n *dbNode) String() string {
+	kids := make([]string, len(n.kids))
+	for i := range n.kids {
+		kids[i] = n.kids[i].String()
+	}
+	switch n.kind {
+	case "bvar":
+		return fmt.Sprintf("#%d", n.index)
+	case "lam", "impl", "pred", "app":
+		return fmt.Sprintf("%s:%s(%s)", n.kind, n.name, strings.Join(kids, ","))
+	default:
+		return fmt.Sprintf("%s:%s", n.kind, n.name)
+	}
+}
+
+func toDBTerm(t Term, env []Variable) *dbNode {
+	switch t := t.(type) {
+	case Variable:
+		for i, v := range env {
+			if v == t {
+				return &dbNode{kind: "bvar", index: i}
+			}
+		}
+		return &dbNode{kind: "fvar", name: string(t)}
+	case application:
+		n := &dbNode{kind: "app", name: t.name}
+		for _, arg := range t.args {
+			n.kids = append(n.kids, toDBTerm(arg, env))
+		}
+		return n
+	default:
+		panic(fmt.Sprintf("unknown term %T", t))
+	}
+}
+
+func toDB(p Proposition, env []Variable) *dbNode {
+	switch p := p.(type) {
+	case Constant:
+		return &dbNode{kind: "const", name: p.String()}
+	case Variable:
+		return &dbNode{kind: "atom", name: string(p)}
+	case implication:
+		return &dbNode{kind: "impl", kids: []*dbNode{
+			toDB(p.antecedent, env), toDB(p.consequent, env),
+		}}
+	case function:
+		n := &dbNode{kind: "pred", name: p.name}
+		for _, arg := range p.args {
+			n.kids = append(n.kids, toDBTerm(arg, env))
+		}
+		return n
+	case lambda:
+		inner := append([]Variable{p.v}, env...)
+		return &dbNode{
+			kind: "lam", name: string(p.q),
+			kids: []*dbNode{toDB(p.scope, inner)},
+		}
+	default:
+		panic(fmt.Sprintf("unknown proposition %T", p))
+	}
+}
+
+// substDB replaces the free variable a with t, which must be closed under
+// the binders of n (true of every term built from free names).
+func substDB(n *dbNode, a string, t *dbNode) *dbNode {
+	if n.kind == "fvar" && n.name == a {
+		return t
+	}
+	m := *n
+	m.kids = make([]*dbNode, len(n.kids))
+	for i := range n.kids {
+		m.kids[i] = substDB(n.kids[i], a, t)
+	}
+	return &m
+}
+
+func freeDB(n *dbNode, m map[string]bool) {
+	if n.kind == "fvar" || n.kind == "atom" {
+		m[n.name] = true
+	}
+	for _, k := range n.kids {
+		freeDB(k, m)
+	}
+}
+
+// fromDB names the bound variables in n by their depth, producing a
+// Proposition alpha-equivalent to the one n was built from.
+func fromDB(n *dbNode, env []Variable) Proposition {
+	switch n.kind {
+	case "const":
+		return Constant(n.name == "true")
+	case "atom":
+		return Variable(n.name)
+	case "impl":
+		return implication{fromDB(n.kids[0], env), fromDB(n.kids[1], env)}
+	case "pred":
+		return function{n.name, fromDBTerms(n.kids, env)}
+	case "lam":
+		v := Variable(fmt.Sprintf("w%d", len(env)))
+		return lambda{
+			quantifier(n.name), v,
+			fromDB(n.kids[0], append([]Variable{v}, env...)),
+		}
+	default:
+		panic(fmt.Sprintf("unknown node %s", n.kind))
+	}
+}
+
+func fromDBTerms(kids []*dbNode, env []Variable) []Term {
+	terms := make([]Term, len(kids))
+	for i, k := range kids {
+		switch k.kind {
+		case "bvar":
+			terms[i] = env[k.index]
+		case "fvar":
+			terms[i] = Variable(k.name)
+		case "app":
+			terms[i] = application{k.name, fromDBTerms(k.kids, env)}
+		}
+	}
+	return terms
+}
+
+func genTerm(r *rand.Rand, depth int) Term {
+	if depth <= 0 || r.Intn(3) > 0 {
+		return individuals[r.Intn(len(individuals))]
+	}
+	if r.Intn(2) == 0 {
+		return Apply("c")
+	}
+	return Apply("f", genTerm(r, depth-1))
+}
+
+func genProp(r *rand.Rand, depth int) Proposition {
+	if depth <= 0 {
+		switch r.Intn(4) {
+		case 0:
+			return Constant(r.Intn(2) == 0)
+		case 1:
+			return atoms[r.Intn(len(atoms))]
+		case 2:
+			return Func("F", genTerm(r, 2))
+		default:
+			return Func("G", genTerm(r, 2), genTerm(r, 2))
+		}
+	}
+	switch r.Intn(3) {
+	case 0:
+		return Impl(genProp(r, depth-1), genProp(r, depth-1))
+	case 1:
+		q := universal
+		if r.Intn(2) == 0 {
+			q = existential
+		}
+		return lambda{
+			q, individuals[r.Intn(len(individuals))],
+			genProp(r, depth-1),
+		}
+	default:
+		return genProp(r, 0)
+	}
+}
+
+type randomProp struct {
+	P Proposition
+}
+
+func (randomProp) Generate(r *rand.Rand, size int) reflect.Value {
+	return reflect.ValueOf(randomProp{genProp(r, 1+r.Intn(5))})
+}
+
+type randomTerm struct {
+	T Term
+}
+
+func (randomTerm) Generate(r *rand.Rand, size int) reflect.Value {
+	return reflect.ValueOf(randomTerm{genTerm(r, 3)})
+}
+
+type randomIndividual struct {
+	V Variable
+}
+
+func (randomIndividual) Generate(r *rand.Rand, size int) reflect.Value {
+	return reflect.ValueOf(randomIndividual{individuals[r.Intn(len(individuals))]})
+}
+
+var quickConfig = &quick.Config{MaxCount: 2000}
+
+func TestSubstitute(t *testing.T) {
+	f := func(p randomProp, a randomIndividual, term randomTerm) bool {
+		got := toDB(p.P.substitute(a.V, term.T), nil).String()
+		want := substDB(toDB(p.P, nil), string(a.V), toDBTerm(term.T, nil))
+		return got == want.String()
+	}
+	if err := quick.Check(f, quickConfig); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReplace(t *testing.T) {
+	f := func(p randomProp, a, b randomIndividual) bool {
+		got := toDB(p.P.replace(a.V, b.V), nil).String()
+		want := substDB(toDB(p.P, nil), string(a.V), toDBTerm(b.V, nil))
+		return got == want.String()
+	}
+	if err := quick.Check(f, quickConfig); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFree(t *testing.T) {
+	f := func(p randomProp) bool {
+		want := map[string]bool{}
+		freeDB(toDB(p.P, nil), want)
+		got := []string{}
+		for _, v := range p.P.free() {
+			got = append(got, string(v))
+		}
+		wantarr := []string{}
+		for v := range want {
+			wantarr = append(wantarr, v)
+		}
+		sort.Strings(got)
+		sort.Strings(wantarr)
+		return reflect.DeepEqual(got, wantarr)
+	}
+	if err := quick.Check(f, quickConfig); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAlphaEquivalence(t *testing.T) {
+	variant := func(p randomProp) bool {
+		q := fromDB(toDB(p.P, nil), nil)
+		return p.P.equals(q) && q.equals(p.P)
+	}
+	if err := quick.Check(variant, quickConfig); err != nil {
+		t.Fatal(err)
+	}
+	pairs := func(p, q randomProp) bool {
+		want := toDB(p.P, nil).String() == toDB(q.P, nil).String()
+		return p.P.equals(q.P) == want
+	}
+	if err := quick.Check(pairs, quickConfig); err != nil {
+		t.Fatal(err)
+	}
+	x, y := Variable("x"), Variable("y")
+	if !Universal("x", Func("F", x)).equals(Universal("y", Func("F", y))) {
+		t.Fatal("(∀x)F(x) differs from (∀y)F(y)")
+	}
+	if Universal("x", Func("F", x, y)).equals(Universal("y", Func("F", y, y))) {
+		t.Fatal("(∀x)F(x, y) equals (∀y)F(y, y)")
+	}
+}
@@ -0,0 +1,105 @@
+package truth
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Term is an individual expression of first-order logic: either a Variable
+// or a function symbol applied to Terms.
+type Term interface {
+	// termFree returns the variables occurring in the Term.
+	termFree() []Variable
+
+	// termSubstitute returns the result of changing occurrences of a to t.
+	termSubstitute(a Variable, t Term) Term
+
+	termEquals(Term) bool
+
+	fmt.Stringer
+}
+
+func (v Variable) termFree() []Variable {
+	return []Variable{v}
+}
+
+func (v Variable) termSubstitute(a Variable, t Term) Term {
+	if v == a {
+		return t
+	}
+	return v
+}
+
+func (v Variable) termEquals(t Term) bool {
+	v1, ok := t.(Variable)
+	return ok && v1 == v
+}
+
+type application struct {
+	name string
+	args []Term
+}
+
+func (app application) termFree() []Variable {
+	return termsFree(app.args)
+}
+
+func (app application) termSubstitute(a Variable, t Term) Term {
+	return application{app.name, termsSubstitute(app.args, a, t)}
+}
+
+func (app application) termEquals(t Term) bool {
+	app2, ok := t.(application)
+	return ok && app.name == app2.name && termsEqual(app.args, app2.args)
+}
+
+func (app application) String() string {
+	return formatApplication(app.name, app.args)
+}
+
+func termsFree(terms []Term) []Variable {
+	vars := []Variable{}
+	m := map[Variable]bool{}
+	for _, t := range terms {
+		for _, v := range t.termFree() {
+			if !m[v] {
+				m[v] = true
+				vars = append(vars, v)
+			}
+		}
+	}
+	return vars
+}
+
+func termsSubstitute(terms []Term, a Variable, t Term) []Term {
+	sub := make([]Term, len(terms))
+	for i := range terms {
+		sub[i] = terms[i].termSubstitute(a, t)
+	}
+	return sub
+}
+
+func termsEqual(s, t []Term) bool {
+	if len(s) != len(t) {
+		return false
+	}
+	for i := range s {
+		if !s[i].termEquals(t[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func formatApplication(name string, args []Term) string {
+	sarr := make([]string, len(args))
+	for i := range args {
+		sarr[i] = args[i].String()
+	}
+	return fmt.Sprintf("%s(%s)", name, strings.Join(sarr, ", "))
+}
+
+// Apply returns the Term formed by applying the function symbol name to args.
+func Apply(name string, args ...Term) Term {
+	return application{name, args}
+}
@@ -1,14 +1,19 @@
 package truth
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"sort"
 	"strings"
+	"time"
+
+	"git.sr.ht/~lbnz/i2/internal/sat"
 )
 
 type Proposition interface {
 	// eval returns the value of the Proposition in the given state.
-	eval(state) bool
+	eval(State) bool
 
 	// free returns the free variables contained in the Proposition.
 	free() []Variable
@@ -16,12 +21,20 @@ type Proposition interface {
 	// replace returns the result of changing free occurrences of a to b.
 	replace(a, b Variable) Proposition
 
+	// substitute returns the result of changing free occurrences of the
+	// individual variable a to t. Propositional variables are unaffected.
+	substitute(a Variable, t Term) Proposition
+
 	// needsBrackets indicates whether the Proposition should be bracketed
 	// when the given operator is applied to it.
 	needsBrackets(operator) bool
 
 	equals(Proposition) bool
 
+	// encode returns a literal standing for the Proposition, adding the
+	// clauses defining it to the encoder.
+	encode(*encoder) (sat.Lit, error)
+
 	fmt.Stringer
 }
 
@@ -55,7 +68,7 @@ func (b Constant) free() []Variable {
 	return []Variable{}
 }
 
-func (b Constant) eval(_ state) bool {
+func (b Constant) eval(_ State) bool {
 	return bool(b)
 }
 
@@ -63,6 +76,10 @@ func (b Constant) replace(_, __ Variable) Proposition {
 	return b
 }
 
+func (b Constant) substitute(_ Variable, __ Term) Proposition {
+	return b
+}
+
 func (b Constant) needsBrackets(_ operator) bool {
 	return false
 }
@@ -74,6 +91,13 @@ func (b Constant) equals(p Proposition) bool {
 	return false
 }
 
+func (b Constant) encode(e *encoder) (sat.Lit, error) {
+	if b {
+		return e.top, nil
+	}
+	return e.top.Not(), nil
+}
+
 func (b Constant) String() string {
 	return fmt.Sprintf("%t", b)
 }
@@ -84,7 +108,7 @@ func (v Variable) free() []Variable {
 	return []Variable{v}
 }
 
-func (v Variable) eval(m state) bool {
+func (v Variable) eval(m State) bool {
 	return m[v]
 }
 
@@ -95,6 +119,10 @@ func (v Variable) replace(a, b Variable) Proposition {
 	return v
 }
 
+func (v Variable) substitute(_ Variable, __ Term) Proposition {
+	return v
+}
+
 func (v Variable) needsBrackets(op operator) bool {
 	return false
 }
@@ -106,22 +134,44 @@ func (v Variable) equals(p Proposition) bool {
 	return false
 }
 
+func (v Variable) encode(e *encoder) (sat.Lit, error) {
+	return e.atom(v), nil
+}
+
 func (v Variable) String() string {
 	return string(v)
 }
 
-type state map[Variable]bool
+// State is an assignment of truth values to the atoms of a Proposition:
+// its propositional variables, atomic formulas and quantified subformulas,
+// the latter two keyed by their String.
+type State map[Variable]bool
+
+// Atoms returns the atoms assigned in the State in lexical order.
+func (m State) Atoms() []Variable {
+	atoms := make([]Variable, 0, len(m))
+	for k := range m {
+		atoms = append(atoms, k)
+	}
+	sort.Slice(atoms, func(i, j int) bool { return atoms[i] < atoms[j] })
+	return atoms
+}
+
+// Eval returns the value of p in the State.
+func (m State) Eval(p Proposition) bool {
+	return p.eval(m)
+}
 
-func (m state) String() string {
+func (m State) String() string {
 	parts := []string{}
-	for k, v := range m {
-		parts = append(parts, fmt.Sprintf("`%s' := %t", k, v))
+	for _, k := range m.Atoms() {
+		parts = append(parts, fmt.Sprintf("`%s' := %t", k, m[k]))
 	}
 	return fmt.Sprintf("{%s}", strings.Join(parts, ", "))
 }
 
 type conflict struct {
-	A, B state
+	A, B State
 	aval bool
 }
 
@@ -130,29 +180,61 @@ func (c *conflict) Error() string {
 		c.A, c.aval, c.B, !c.aval)
 }
 
-func states(vars []Variable) []state {
-	if len(vars) == 0 {
-		return []state{state{}}
-	}
-	var asms []state
-	for _, subasm := range states(vars[1:]) {
-		f, t := state{vars[0]: false},
-			state{vars[0]: true}
-		for k, v := range subasm {
-			f[k], t[k] = v, v
-		}
-		asms = append(asms, f, t)
+// Atom returns the atom standing for p in a State if p is atomic: a
+// propositional variable, an atomic formula or a quantified formula.
+func Atom(p Proposition) (Variable, bool) {
+	switch p := p.(type) {
+	case Variable:
+		return p, true
+	case function:
+		return p.atom(), true
+	case equality:
+		return p.atom(), true
+	case lambda:
+		return p.atom(), true
+	default:
+		return "", false
 	}
-	return asms
 }
 
+// Falsify returns a State in which p is false, or nil if p is valid when its
+// quantified subformulas are treated as opaque.
+func Falsify(p Proposition) (State, error) {
+	return satisfy(Not(p))
+}
+
+// DefaultTimeout bounds the time Decide spends on first-order Propositions.
+var DefaultTimeout = 5 * time.Second
+
+// Decide returns the value of p if it is the same in every state, and a
+// conflict error otherwise. It gives up on quantified Propositions after
+// DefaultTimeout, returning an error wrapping ErrUnknown.
 func Decide(p Proposition) (bool, error) {
-	asms := states(p.free())
-	v0 := p.eval(asms[0])
-	for _, asm := range asms[1:] {
-		if v0 != p.eval(asm) {
-			return false, &conflict{A: asms[0], B: asm, aval: v0}
-		}
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultTimeout)
+	defer cancel()
+	return DecideContext(ctx, p)
+}
+
+// DecideContext is like Decide but bounded by ctx rather than DefaultTimeout.
+func DecideContext(ctx context.Context, p Proposition) (bool, error) {
+	// quantified subformulas are opaque to the propositional encoding, so
+	// its verdict is definitive only if it is valid or unsatisfiable
+	falsifier, err := satisfy(Not(p))
+	if err != nil {
+		return false, err
+	}
+	if falsifier == nil {
+		return true, nil
+	}
+	verifier, err := satisfy(p)
+	if err != nil {
+		return false, err
+	}
+	if verifier == nil {
+		return false, nil
+	}
+	if !quantified(p) {
+		return false, &conflict{A: verifier, B: falsifier, aval: true}
 	}
-	return v0, nil
+	return decideFirstOrder(ctx, p)
 }
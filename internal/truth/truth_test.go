@@ -1,6 +1,7 @@
 package truth
 
 import (
+	"fmt"
 	"testing"
 )
 
@@ -57,3 +58,119 @@ func TestAdvanced2(t *testing.T) {
 		Universal("x", And(p, Func("F", x)))
 	Eqv(p0, p1)
 }
+
+func TestManyAtoms(t *testing.T) {
+	// p0 ==> p1, p1 ==> p2, ..., p39 ==> p40 |- p0 ==> p40
+	const n = 40
+	var hyp Proposition = Constant(true)
+	for i := 0; i < n; i++ {
+		hyp = And(hyp, Impl(
+			Variable(fmt.Sprintf("p%d", i)),
+			Variable(fmt.Sprintf("p%d", i+1)),
+		))
+	}
+	impl := Impl(hyp, Impl(Variable("p0"), Variable(fmt.Sprintf("p%d", n))))
+	b, err := Decide(impl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !b {
+		t.Fatalf("%s failed", impl)
+	}
+}
+
+func TestContingent(t *testing.T) {
+	p, q := Variable("p"), Variable("q")
+	impl := Impl(Or(p, q), p)
+	_, err := Decide(impl)
+	c, ok := err.(*conflict)
+	if !ok {
+		t.Fatalf("expected conflict, got %v", err)
+	}
+	if impl.eval(c.A) != c.aval || impl.eval(c.B) == c.aval {
+		t.Fatalf("bad conflict %s", c)
+	}
+	if b, err := Decide(And(p, Not(p))); err != nil || b {
+		t.Fatalf("contradiction decided as %t, %v", b, err)
+	}
+}
+
+func TestFirstOrder(t *testing.T) {
+	x, p := Variable("x"), Variable("p")
+	one := Apply("1")
+	succ := func(t Term) Term { return Apply("succ", t) }
+	P := func(t Term) Proposition { return Func("P", t) }
+	valid := []Proposition{
+		// p && (∀x)F(x) === (∀x)(p && F(x))
+		Eqv(
+			And(p, Universal("x", Func("F", x))),
+			Universal("x", And(p, Func("F", x))),
+		),
+		// (∀x)F(x) ==> F(a)
+		Impl(Universal("x", Func("F", x)), Func("F", Variable("a"))),
+		// (∀x)F(x) ==> (∃x)F(x)
+		Impl(Universal("x", Func("F", x)), Existential("x", Func("F", x))),
+		// P(1) && (∀x)(P(x) ==> P(succ(x))) ==> P(succ(succ(1)))
+		Impl(
+			And(P(one), Universal("x", Impl(P(x), P(succ(x))))),
+			P(succ(succ(one))),
+		),
+		// (∃y)(∀x)R(x, y) ==> (∀x)(∃y)R(x, y)
+		Impl(
+			Existential("y", Universal("x", Func("R", x, Variable("y")))),
+			Universal("x", Existential("y", Func("R", x, Variable("y")))),
+		),
+		// (∀x)(∀y)R(x, y) ==> (∀y)(∀x)R(y, x)
+		Impl(
+			Universal("x", Universal("y", Func("R", x, Variable("y")))),
+			Universal("y", Universal("x", Func("R", Variable("y"), x))),
+		),
+	}
+	for _, prop := range valid {
+		b, err := Decide(prop)
+		if err != nil {
+			t.Fatalf("%s: %s", prop, err)
+		}
+		if !b {
+			t.Fatalf("%s failed", prop)
+		}
+	}
+	// F(a) ==> (∀x)F(x)
+	prop := Impl(Func("F", Variable("a")), Universal("x", Func("F", x)))
+	if _, err := Decide(prop); err == nil {
+		t.Fatalf("%s decided", prop)
+	}
+	// (∀x)F(x) && (∃x)!F(x)
+	prop = And(Universal("x", Func("F", x)), Existential("x", Not(Func("F", x))))
+	if b, err := Decide(prop); err != nil || b {
+		t.Fatalf("%s decided as %t, %v", prop, b, err)
+	}
+}
+
+func TestEquality(t *testing.T) {
+	a, b, c, x := Variable("a"), Variable("b"), Variable("c"), Variable("x")
+	succ := func(t Term) Term { return Apply("succ", t) }
+	P := func(t Term) Proposition { return Func("P", t) }
+	for _, p := range []Proposition{
+		Eq(a, a),
+		Impl(Eq(a, b), Eq(b, a)),
+		Impl(And(Eq(a, b), Eq(b, c)), Eq(a, c)),
+		Impl(Eq(a, b), Eq(succ(a), succ(b))),
+		Impl(And(Eq(a, b), P(a)), P(b)),
+		Impl(Eq(succ(succ(a)), a), Eq(succ(succ(succ(succ(a)))), a)),
+		Impl(Universal("x", Eq(succ(x), x)), Eq(succ(succ(a)), a)),
+	} {
+		if val, err := Decide(p); err != nil || !val {
+			t.Errorf("%s: expected valid, got %t (%v)", p, val, err)
+		}
+	}
+	for _, p := range []Proposition{
+		Eq(a, b),
+		Impl(Eq(succ(a), succ(b)), Eq(a, b)),
+		Impl(P(a), P(b)),
+	} {
+		if val, err := Decide(p); err == nil || val {
+			t.Errorf("%s: expected contingent, got %t (%v)", p, val, err)
+		}
+	}
+}
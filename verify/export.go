@@ -0,0 +1,53 @@
+package verify
+
+import (
+	"context"
+	"fmt"
+
+	"git.sr.ht/~lbnz/i2/internal/parser"
+)
+
+// Script is the SMT-LIB 2 script of the obligation that a step holds, which
+// is valid if the script is unsatisfiable.
+type Script struct {
+	// Template is that whose proof the step is in, and Index the position
+	// of the step among those of its proofs, counted from 1.
+	Template string `json:"template"`
+	Index    int    `json:"index"`
+
+	// Step is the step as written, at Span.
+	Step string `json:"step"`
+	Span Span   `json:"span"`
+
+	Source string `json:"source"`
+}
+
+// Name returns the name of the file for s, after its template and step
+// index.
+func (s Script) Name() string {
+	return fmt.Sprintf("%s-%d.smt2", s.Template, s.Index)
+}
+
+// Export translates the steps of the proofs of source, with what they cite,
+// into SMT-LIB 2 scripts, so that they may be checked by other solvers. The
+// types of i2 are sorts, each an alias of `any'. The steps that cannot be
+// analysed have no script. The error is that of ctx if it ends first.
+func Export(ctx context.Context, source string, opts Options) ([]Script, error) {
+	cfg := opts.config()
+	cfg.SMTLIB = true
+	res, err := parser.Verify(ctx, source, cfg)
+	if err != nil {
+		return nil, err
+	}
+	scripts := make([]Script, len(res.Scripts))
+	for i, s := range res.Scripts {
+		scripts[i] = Script{
+			Template: s.Template,
+			Index:    s.Index,
+			Step:     s.Step.String(),
+			Span:     s.Step.Extent(),
+			Source:   s.Source,
+		}
+	}
+	return scripts, nil
+}
@@ -0,0 +1,44 @@
+package verify
+
+import (
+	"context"
+
+	"git.sr.ht/~lbnz/i2/internal/parser"
+)
+
+// Hint is a citation found to justify a step that is unjustified or fails.
+type Hint struct {
+	// Step is the step as written, at Span.
+	Step string `json:"step"`
+	Span Span   `json:"span"`
+
+	// Citation justifies the step together with what it already cites.
+	Citation string `json:"citation"`
+
+	// Size is the number of symbols in the Citation. Smaller citations are
+	// listed first.
+	Size int `json:"size"`
+}
+
+// Suggest seeks citations that justify the steps of source on line that are
+// unjustified or fail. It tries the templates and labelled proofs in scope
+// of each step, instantiating templates with the terms of the step that
+// their parameters accept. The error is that of ctx if it ends first.
+func Suggest(ctx context.Context, source string, opts Options, line int) ([]Hint, error) {
+	cfg := opts.config()
+	cfg.HintLine = line
+	res, err := parser.Verify(ctx, source, cfg)
+	if err != nil {
+		return nil, err
+	}
+	hints := make([]Hint, len(res.Hints))
+	for i, h := range res.Hints {
+		hints[i] = Hint{
+			Step:     h.Step.BinaryOpExpr.String(),
+			Span:     h.Step.Extent(),
+			Citation: h.Citation.String(),
+			Size:     h.Size,
+		}
+	}
+	return hints, nil
+}
@@ -0,0 +1,66 @@
+package verify
+
+import (
+	"context"
+	"time"
+
+	"git.sr.ht/~lbnz/i2/internal/parser"
+)
+
+// SearchOptions configure Search. Zero limits take their defaults.
+type SearchOptions struct {
+	// Line is the line of the steps whose gaps are filled.
+	Line int
+
+	// Depth bounds the steps of each chain; by default it is 3.
+	Depth int
+
+	// Timeout bounds the search for each chain; by default it is 10s.
+	Timeout time.Duration
+}
+
+// Fill is the outcome of the search for a chain proving a failing step.
+type Fill struct {
+	// Step is the step as written, at Span.
+	Step string `json:"step"`
+	Span Span   `json:"span"`
+
+	// Chain is the i2 source of a chain of justified steps proving the
+	// step, or empty if none was found, in which case Diagnostic explains
+	// why.
+	Chain      string      `json:"chain,omitempty"`
+	Diagnostic *Diagnostic `json:"diagnostic,omitempty"`
+}
+
+// Search seeks chains of justified steps proving the failing steps of source
+// on the line of s, given only their first and last expressions. It deepens
+// the chains one step at a time, each justified by a template or labelled
+// proof in scope. The error is that of ctx if it ends first.
+func Search(ctx context.Context, source string, opts Options, s SearchOptions) ([]Fill, error) {
+	if s.Depth == 0 {
+		s.Depth = 3
+	}
+	if s.Timeout == 0 {
+		s.Timeout = 10 * time.Second
+	}
+	cfg := opts.config()
+	cfg.Search = &parser.Search{
+		Line: s.Line, Depth: s.Depth, Timeout: s.Timeout,
+	}
+	res, err := parser.Verify(ctx, source, cfg)
+	if err != nil {
+		return nil, err
+	}
+	fills := make([]Fill, len(res.Fills))
+	for i, f := range res.Fills {
+		fills[i] = Fill{
+			Step:       f.Step.BinaryOpExpr.String(),
+			Span:       f.Step.Extent(),
+			Diagnostic: f.Diagnostic,
+		}
+		if f.Chain != nil {
+			fills[i].Chain = f.Source()
+		}
+	}
+	return fills, nil
+}
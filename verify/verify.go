@@ -0,0 +1,341 @@
+// Package verify checks i2 source for use by programs embedding the verifier.
+//
+// Verify reports on every template in the source, the proofs given for it and
+// the outcome of each proof obligation, together with the problems found. A
+// Report is plain data: it refers to the source only through strings and
+// spans, so it can be kept and inspected after verification.
+package verify
+
+import (
+	"context"
+	"time"
+
+	"git.sr.ht/~lbnz/i2/internal/diag"
+	"git.sr.ht/~lbnz/i2/internal/parser"
+	"git.sr.ht/~lbnz/i2/internal/symbol"
+	"git.sr.ht/~lbnz/i2/internal/truth"
+)
+
+type (
+	Pos        = diag.Pos
+	Span       = diag.Span
+	Severity   = diag.Severity
+	Code       = diag.Code
+	Diagnostic = diag.Diagnostic
+	Outcome    = parser.Outcome
+)
+
+const (
+	Error   = diag.Error
+	Warning = diag.Warning
+	Info    = diag.Info
+)
+
+const (
+	Skipped   = parser.Skipped
+	Proven    = parser.Proven
+	Refuted   = parser.Refuted
+	Undecided = parser.Undecided
+	Invalid   = parser.Invalid
+)
+
+// Options configure Verify. The zero value is ready to use.
+type Options struct {
+	// Timeout bounds the time spent deciding each proof obligation. If
+	// zero, truth.DefaultTimeout is used.
+	Timeout time.Duration
+
+	// File names the source. Imports are sought relative to its directory
+	// and those enclosing it, or to the working directory if it is empty.
+	File string
+
+	// Importer loads imported modules. If it is nil, a new Importer with
+	// an empty search path is used.
+	Importer *Importer
+
+	// Lint adds to the Diagnostics warnings of citations that steps do not
+	// need, preamble proofs and axioms that are never used, and suggestions
+	// of consecutive steps that may be merged.
+	Lint bool
+}
+
+// Importer loads the modules named by import statements, caching them so that
+// each is verified once however many times it is imported, until its file
+// changes. It is safe for concurrent use.
+type Importer struct {
+	fi *parser.FileImporter
+}
+
+// NewImporter returns an Importer that seeks modules in the directories of
+// searchPath after those enclosing the importing file.
+func NewImporter(searchPath ...string) *Importer {
+	return &Importer{parser.NewFileImporter(searchPath)}
+}
+
+// Obligation is the attempt to discharge a single proof obligation.
+type Obligation struct {
+	Outcome  Outcome       `json:"outcome"`
+	Duration time.Duration `json:"duration_ns"`
+
+	// Diagnostic explains why the obligation was not discharged; it is nil
+	// if the obligation is Proven or Skipped.
+	Diagnostic *Diagnostic `json:"diagnostic,omitempty"`
+}
+
+// Step is the obligation that one link of a proof holds.
+type Step struct {
+	Expr string `json:"expr"`
+	Span Span   `json:"span"`
+
+	// Justification is the template instance cited by the step, if any.
+	Justification string `json:"justification,omitempty"`
+	Obligation
+}
+
+// Lemma is a proof from the preamble of a proof block, citable by its Label
+// in what follows it.
+type Lemma struct {
+	Label string `json:"label,omitempty"`
+	Span  Span   `json:"span"`
+	Steps []Step `json:"steps"`
+}
+
+// Proof is a proof block given for a template.
+type Proof struct {
+	Span     Span    `json:"span"`
+	Preamble []Lemma `json:"preamble"`
+	Steps    []Step  `json:"steps"`
+
+	// Subproofs are the proofs of the obligations into which a proof by
+	// induction, by cases or by assumption divides the assertion. Such a
+	// proof has no Steps of its own.
+	Subproofs []Subproof `json:"subproofs,omitempty"`
+
+	// QED is the obligation that the proof establishes the assertion of
+	// the template. It is Skipped if any step or subproof fails.
+	QED Obligation `json:"qed"`
+}
+
+// Subproof is the proof of one obligation of a structured proof.
+type Subproof struct {
+	// Label names the obligation, such as `base' or `step'.
+	Label string `json:"label"`
+	Goal  string `json:"goal"`
+	Proof
+}
+
+// Parameter is a parameter of a function or template.
+type Parameter struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// Function is a `func' statement.
+type Function struct {
+	Name      string      `json:"name"`
+	IsAxiom   bool        `json:"axiom"`
+	Params    []Parameter `json:"params"`
+	Return    string      `json:"return"`
+	Signature string      `json:"signature"`
+	Span      Span        `json:"span"`
+}
+
+// Term is a `term' statement.
+type Term struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	Span Span   `json:"span"`
+}
+
+// Template is a `tmpl' statement.
+type Template struct {
+	Name    string      `json:"name"`
+	IsAxiom bool        `json:"axiom"`
+	Params  []Parameter `json:"params"`
+
+	// Assertion is the expression asserted, in the syntax of i2.
+	Assertion string  `json:"assertion"`
+	Statement string  `json:"statement"`
+	Span      Span    `json:"span"`
+	Proofs    []Proof `json:"proofs"`
+}
+
+// Import is a symbol bound by an import statement, declared by one of
+// Function, Term and Template, whose Span lies in File.
+type Import struct {
+	// Name is that by which the source refers to the symbol, qualified by
+	// the name of its module or not.
+	Name     string    `json:"name"`
+	File     string    `json:"file"`
+	Function *Function `json:"function,omitempty"`
+	Term     *Term     `json:"term,omitempty"`
+	Template *Template `json:"template,omitempty"`
+}
+
+// Report is the outcome of verifying a source file.
+type Report struct {
+	// Functions, Terms and Templates are in the order of their statements,
+	// and Imports in that of the import statements binding them.
+	Functions   []Function   `json:"functions"`
+	Terms       []Term       `json:"terms"`
+	Templates   []Template   `json:"templates"`
+	Imports     []Import     `json:"imports"`
+	Diagnostics []Diagnostic `json:"diagnostics"`
+}
+
+// OK indicates whether the source verified without errors. An obligation
+// that could not be decided is reported as a warning, but is not verified.
+func (r *Report) OK() bool {
+	if diag.HasErrors(r.Diagnostics) {
+		return false
+	}
+	for _, d := range r.Diagnostics {
+		if d.Code == diag.Undecided {
+			return false
+		}
+	}
+	return true
+}
+
+// Verify parses and verifies source. Problems with the source are reported
+// as Diagnostics rather than as the error, which is that of ctx if it ends
+// before verification does.
+func Verify(ctx context.Context, source string, opts Options) (*Report, error) {
+	res, err := parser.Verify(ctx, source, opts.config())
+	if err != nil {
+		return nil, err
+	}
+	r := &Report{
+		Functions:   make([]Function, len(res.Functions)),
+		Terms:       make([]Term, len(res.Terms)),
+		Templates:   make([]Template, len(res.Templates)),
+		Imports:     make([]Import, len(res.Imports)),
+		Diagnostics: res.Diagnostics,
+	}
+	if r.Diagnostics == nil {
+		r.Diagnostics = []Diagnostic{}
+	}
+	for i, f := range res.Functions {
+		r.Functions[i] = function(f)
+	}
+	for i, t := range res.Terms {
+		r.Terms[i] = Term{Name: t.Name, Type: t.Type.String(), Span: t.Span}
+	}
+	for i, t := range res.Templates {
+		r.Templates[i] = template(t)
+	}
+	for i, imp := range res.Imports {
+		r.Imports[i] = Import{Name: imp.Name, File: imp.Module.File}
+		switch sym := imp.Symbol.(type) {
+		case symbol.Function:
+			f := function(sym)
+			r.Imports[i].Function = &f
+		case symbol.Template:
+			t := template(parser.TemplateResult{Template: sym})
+			r.Imports[i].Template = &t
+		case symbol.Type:
+			r.Imports[i].Term = &Term{
+				Name: imp.Name, Type: sym.String(), Span: imp.Span,
+			}
+		}
+	}
+	return r, nil
+}
+
+func function(f symbol.Function) Function {
+	return Function{
+		Name:      f.Name,
+		IsAxiom:   f.IsAxiom,
+		Params:    params(f.Sig.Params),
+		Return:    f.Sig.Return.String(),
+		Signature: f.String(),
+		Span:      f.Span,
+	}
+}
+
+// config returns the configuration of the parser given by opts.
+func (opts Options) config() parser.Config {
+	if opts.Timeout == 0 {
+		opts.Timeout = truth.DefaultTimeout
+	}
+	if opts.Importer == nil {
+		opts.Importer = NewImporter()
+	}
+	return parser.Config{
+		Timeout:  opts.Timeout,
+		File:     opts.File,
+		Importer: opts.Importer.fi,
+		Lint:     opts.Lint,
+	}
+}
+
+func template(t parser.TemplateResult) Template {
+	tmpl := Template{
+		Name:      t.Template.Name,
+		IsAxiom:   t.Template.IsAxiom,
+		Params:    params(t.Template.Params),
+		Assertion: symbol.Source(t.Template.E),
+		Statement: t.Template.String(),
+		Span:      t.Template.Span,
+		Proofs:    make([]Proof, len(t.Proofs)),
+	}
+	for i, p := range t.Proofs {
+		tmpl.Proofs[i] = proof(p)
+	}
+	return tmpl
+}
+
+func proof(p parser.ProofResult) Proof {
+	prf := Proof{
+		Span:     p.Chain.Span,
+		Preamble: make([]Lemma, len(p.Preamble)),
+		Steps:    steps(p.Steps),
+		QED:      obligation(p.QED),
+	}
+	for j, l := range p.Preamble {
+		prf.Preamble[j] = Lemma{
+			Label: l.Proof.Label(),
+			Span:  l.Proof.Extent(),
+			Steps: steps(l.Steps),
+		}
+	}
+	for _, sub := range p.Subproofs {
+		prf.Subproofs = append(prf.Subproofs, Subproof{
+			Label: sub.Label,
+			Goal:  sub.Goal.String(),
+			Proof: proof(sub.ProofResult),
+		})
+	}
+	return prf
+}
+
+func params(arr []symbol.Parameter) []Parameter {
+	p := make([]Parameter, len(arr))
+	for i, param := range arr {
+		p[i] = Parameter{Name: param.Name, Type: param.Type.String()}
+	}
+	return p
+}
+
+func steps(arr []parser.Step) []Step {
+	s := make([]Step, len(arr))
+	for i, step := range arr {
+		s[i] = Step{
+			Expr:       step.Expr.String(),
+			Span:       step.Expr.Extent(),
+			Obligation: obligation(step.Obligation),
+		}
+		if step.Expr.Just != nil {
+			s[i].Justification = step.Expr.Just.String()
+		}
+	}
+	return s
+}
+
+func obligation(o parser.Obligation) Obligation {
+	return Obligation{
+		Outcome:    o.Outcome,
+		Duration:   o.Duration,
+		Diagnostic: o.Diagnostic,
+	}
+}
@@ -0,0 +1,182 @@
+package verify
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"git.sr.ht/~lbnz/i2/internal/diag"
+)
+
+func TestVerify(t *testing.T) {
+	const file = "../examples/landau/addition.i2"
+	input, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := Verify(context.Background(), string(input), Options{File: file})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.OK() {
+		t.Fatalf("unexpected diagnostics %v", r.Diagnostics)
+	}
+	if len(r.Templates) == 0 {
+		t.Fatal("no templates reported")
+	}
+	proofs, justified := 0, 0
+	for _, tmpl := range r.Templates {
+		if tmpl.Name == "" || !tmpl.Span.IsValid() {
+			t.Fatalf("incomplete template %+v", tmpl)
+		}
+		for _, prf := range tmpl.Proofs {
+			proofs++
+			if prf.QED.Outcome != Proven {
+				t.Fatalf("%s: qed %s", tmpl.Name, prf.QED.Outcome)
+			}
+			for _, step := range prf.Steps {
+				if step.Outcome != Proven || step.Diagnostic != nil {
+					t.Fatalf("%s: step `%s' %s", tmpl.Name, step.Expr,
+						step.Outcome)
+				}
+				if step.Justification != "" {
+					justified++
+				}
+			}
+		}
+	}
+	if proofs == 0 || justified == 0 {
+		t.Fatalf("%d proofs with %d justified steps", proofs, justified)
+	}
+}
+
+func TestVerifyFailure(t *testing.T) {
+	input := `@func p(x any) bool;
+tmpl a() { p(1) } {
+	true
+==>	p(1);
+};`
+	r, err := Verify(context.Background(), input, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.OK() || len(r.Templates) != 1 {
+		t.Fatalf("unexpected report %+v", r)
+	}
+	step := r.Templates[0].Proofs[0].Steps[0]
+	if step.Outcome != Refuted || step.Diagnostic == nil {
+		t.Fatalf("unexpected step %+v", step)
+	}
+	if step.Span.Start.Line != 3 {
+		t.Fatalf("step at %s", step.Span)
+	}
+}
+
+func TestVerifyCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := Verify(ctx, "", Options{}); err != context.Canceled {
+		t.Fatalf("expected %v, got %v", context.Canceled, err)
+	}
+}
+
+// verified are the examples expected to verify without problems.
+var verified = map[string]bool{
+	"halmos/zfc.i2":                true,
+	"landau/addition.i2":           true,
+	"landau/addition-induction.i2": true,
+	"landau/peano.i2":              true,
+	"landau/succ-distinct.i2":      true,
+	"landau/website.i2":            true,
+}
+
+// TestVerifyConcurrently verifies every example at once, sharing an importer,
+// checking that the reports agree with those of verifying them one at a time.
+// Run it with -race to check that verifications share no state.
+func TestVerifyConcurrently(t *testing.T) {
+	files, err := filepath.Glob("../examples/*/*.i2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("no examples")
+	}
+	const rounds = 4
+	sources := make([]string, len(files))
+	want := make([]*Report, len(files))
+	for i, file := range files {
+		input, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sources[i] = string(input)
+		want[i], err = Verify(
+			context.Background(), sources[i], Options{File: file},
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, d := range want[i].Diagnostics {
+			if d.Code == diag.Import {
+				t.Fatalf("%s: %s", file, d.Message)
+			}
+		}
+		name := filepath.ToSlash(strings.TrimPrefix(file, "../examples/"))
+		if verified[name] && !want[i].OK() {
+			t.Fatalf("%s: unexpected diagnostics %v", file, want[i].Diagnostics)
+		}
+	}
+	importer := NewImporter()
+	var wg sync.WaitGroup
+	got := make([]*Report, rounds*len(files))
+	errs := make([]error, rounds*len(files))
+	for i := range got {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			got[i], errs[i] = Verify(
+				context.Background(), sources[i%len(files)], Options{
+					File:     files[i%len(files)],
+					Importer: importer,
+				},
+			)
+		}(i)
+	}
+	wg.Wait()
+	for i := range got {
+		file := files[i%len(files)]
+		if errs[i] != nil {
+			t.Fatalf("%s: %s", file, errs[i])
+		}
+		name := filepath.ToSlash(strings.TrimPrefix(file, "../examples/"))
+		if verified[name] && !got[i].OK() {
+			t.Fatalf("%s: unexpected diagnostics %v", file, got[i].Diagnostics)
+		}
+		if !reflect.DeepEqual(outline(got[i]), outline(want[i%len(files)])) {
+			t.Fatalf("%s: reports differ", file)
+		}
+	}
+}
+
+// outline returns the parts of r independent of timing. The messages of
+// diagnostics are not, as those of undecided steps may vary.
+func outline(r *Report) []any {
+	o := []any{}
+	for _, d := range r.Diagnostics {
+		o = append(o, d.Severity, d.Code, d.Span)
+	}
+	for _, tmpl := range r.Templates {
+		o = append(o, tmpl.Name, tmpl.Statement)
+		for _, prf := range tmpl.Proofs {
+			o = append(o, prf.QED.Outcome)
+			for _, step := range prf.Steps {
+				o = append(o, step.Expr, step.Outcome)
+			}
+		}
+	}
+	return o
+}